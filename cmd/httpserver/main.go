@@ -3,6 +3,7 @@ package main
 import (
 	"crypto/sha256"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"io"
 	"log"
@@ -12,16 +13,28 @@ import (
 	"strings"
 	"syscall"
 
+	"github.com/UUest/httpfromtcp/internal/compress"
+	"github.com/UUest/httpfromtcp/internal/fileserver"
 	"github.com/UUest/httpfromtcp/internal/headers"
+	"github.com/UUest/httpfromtcp/internal/negotiate"
 	"github.com/UUest/httpfromtcp/internal/request"
 	"github.com/UUest/httpfromtcp/internal/response"
 	"github.com/UUest/httpfromtcp/internal/server"
+	"github.com/UUest/httpfromtcp/internal/urlpath"
 )
 
 const port = 42069
 
 func main() {
-	server, err := server.Serve(port, handler)
+	mux := server.NewMux()
+	mux.Handle("GET", "/yourproblem", handler400)
+	mux.Handle("GET", "/myproblem", handler500)
+	mux.Handle("GET", "/video", handlerVideo)
+	mux.Handle("GET", "/httpbin", handlerProxy)
+	mux.Handle("GET", "/httpbin/", handlerProxy)
+	mux.Handle("GET", "/", handler200)
+
+	server, err := server.Serve(port, server.CleanPath(compress.Middleware(mux.ServeRequest), urlpath.TrailingSlashKeep, true), server.WithMaxBodySize(request.DefaultMaxFormSize))
 	if err != nil {
 		log.Fatalf("Error starting server: %v", err)
 	}
@@ -34,83 +47,60 @@ func main() {
 	log.Println("Server gracefully stopped")
 }
 
-func handler(w *response.Writer, req *request.Request) {
-	if req.RequestLine.RequestTarget == "/yourproblem" {
-		handler400(w, req)
-		return
-	}
-	if req.RequestLine.RequestTarget == "/myproblem" {
-		handler500(w, req)
-		return
-	}
-	if req.RequestLine.RequestTarget == "/video" {
-		handlerVideo(w, req)
-		return
-	}
-	if strings.HasPrefix(req.RequestLine.RequestTarget, "/httpbin") {
-		handlerProxy(w, req)
-		return
-	}
-	handler200(w, req)
+func handler400(w *response.Writer, req *request.Request) {
+	writePage(w, req, response.StatusCodeBadRequest, "400 Bad Request", "Bad Request", "Your request honestly kinda sucked.")
 }
 
-func handler400(w *response.Writer, _ *request.Request) {
-	w.WriteStatusLine(response.StatusCodeBadRequest)
-	body := []byte(`<html>
-<head>
-<title>400 Bad Request</title>
-</head>
-<body>
-<h1>Bad Request</h1>
-<p>Your request honestly kinda sucked.</p>
-</body>
-</html>
-`)
-	h := response.GetDefaultHeaders(len(body))
-	h.Override("Content-Type", "text/html")
-	w.WriteHeaders(h)
-	w.WriteBody(body)
+func handler500(w *response.Writer, req *request.Request) {
+	writePage(w, req, response.StatusCodeInternalServerError, "500 Internal Server Error", "Internal Server Error", "Okay, you know what? This one is on me.")
 }
 
-func handler500(w *response.Writer, _ *request.Request) {
-	w.WriteStatusLine(response.StatusCodeInternalServerError)
-	body := []byte(`<html>
-<head>
-<title>500 Internal Server Error</title>
-</head>
-<body>
-<h1>Internal Server Error</h1>
-<p>Okay, you know what? This one is on me.</p>
-</body>
-</html>
-`)
-	h := response.GetDefaultHeaders(len(body))
-	h.Override("Content-Type", "text/html")
-	w.WriteHeaders(h)
-	w.WriteBody(body)
+func handler200(w *response.Writer, req *request.Request) {
+	writePage(w, req, response.StatusCodeSuccess, "200 OK", "Success!", "Your request was an absolute banger.")
 }
 
-func handler200(w *response.Writer, _ *request.Request) {
-	w.WriteStatusLine(response.StatusCodeSuccess)
-	body := []byte(`<html>
+var pageOffers = []string{"text/html", "application/json"}
+
+// writePage answers with an HTML page for browsers or a small JSON document
+// for API clients, depending on the Accept header.
+func writePage(w *response.Writer, req *request.Request, statusCode response.StatusCode, title, heading, message string) {
+	contentType, ok := negotiate.ContentType(req.Headers, pageOffers)
+	if !ok {
+		negotiate.WriteNotAcceptable(w, pageOffers)
+		return
+	}
+	var body []byte
+	if contentType == "application/json" {
+		body, _ = json.Marshal(map[string]any{
+			"status":  int(statusCode),
+			"message": message,
+		})
+	} else {
+		body = []byte(fmt.Sprintf(`<html>
 <head>
-<title>200 OK</title>
+<title>%s</title>
 </head>
 <body>
-<h1>Success!</h1>
-<p>Your request was an absolute banger.</p>
+<h1>%s</h1>
+<p>%s</p>
 </body>
 </html>
-`)
+`, title, heading, message))
+	}
+	w.WriteStatusLine(statusCode)
 	h := response.GetDefaultHeaders(len(body))
-	h.Override("Content-Type", "text/html")
+	h.Override("Content-Type", contentType)
+	h.Set("Vary", "Accept")
 	w.WriteHeaders(h)
 	w.WriteBody(body)
 }
 
 func handlerProxy(w *response.Writer, r *request.Request) {
-	target := strings.TrimPrefix(r.RequestLine.RequestTarget, "/httpbin/")
+	target := strings.TrimPrefix(strings.TrimPrefix(r.Target.RawPath, "/httpbin"), "/")
 	targetUrl := "https://httpbin.org/" + target
+	if r.Target.RawQuery != "" {
+		targetUrl += "?" + r.Target.RawQuery
+	}
 	resp, err := http.Get(targetUrl)
 	if err != nil {
 		handler500(w, r)
@@ -161,15 +151,7 @@ func handlerProxy(w *response.Writer, r *request.Request) {
 	w.WriteTrailers(trailers)
 }
 
-func handlerVideo(w *response.Writer, _ *request.Request) {
+func handlerVideo(w *response.Writer, req *request.Request) {
 	const videoFile = "assets/vim.mp4"
-	body, err := os.ReadFile(videoFile)
-	if err != nil {
-		fmt.Println("Error reading video file:", err)
-	}
-	w.WriteStatusLine(response.StatusCodeSuccess)
-	h := response.GetDefaultHeaders(len(body))
-	h.Override("Content-Type", "video/mp4")
-	w.WriteHeaders(h)
-	w.WriteBody(body)
+	fileserver.ServeFile(w, req, videoFile)
 }
@@ -0,0 +1,170 @@
+package compress
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"strings"
+
+	"github.com/UUest/httpfromtcp/internal/headers"
+	"github.com/UUest/httpfromtcp/internal/negotiate"
+	"github.com/UUest/httpfromtcp/internal/request"
+	"github.com/UUest/httpfromtcp/internal/response"
+	"github.com/UUest/httpfromtcp/internal/server"
+)
+
+// DefaultMinSize is the smallest Content-Length worth compressing. Bodies of
+// unknown length are always compressed.
+const DefaultMinSize = 1024
+
+// EncoderFunc wraps w with a compressor for one content coding. Closing the
+// returned writer must flush everything to w but not close w. Encoders for
+// streamed responses should also have a Flush() error method, like gzip and
+// zlib writers, so each chunk reaches the client without waiting for Close.
+type EncoderFunc func(w io.Writer) io.WriteCloser
+
+// Registry maps content codings to encoders, in order of preference.
+type Registry struct {
+	codings  []string
+	encoders map[string]EncoderFunc
+}
+
+// NewRegistry returns a Registry with gzip and deflate.
+func NewRegistry() *Registry {
+	r := &Registry{encoders: map[string]EncoderFunc{}}
+	r.Register("deflate", func(w io.Writer) io.WriteCloser {
+		return zlib.NewWriter(w)
+	})
+	r.Register("gzip", func(w io.Writer) io.WriteCloser {
+		return gzip.NewWriter(w)
+	})
+	return r
+}
+
+// Register adds or replaces the encoder for coding, e.g. "br" backed by a
+// brotli library. The coding is preferred over the ones registered before it
+// when the client likes them equally.
+func (r *Registry) Register(coding string, fn EncoderFunc) {
+	coding = strings.ToLower(coding)
+	for i, c := range r.codings {
+		if c == coding {
+			r.codings = append(r.codings[:i], r.codings[i+1:]...)
+			break
+		}
+	}
+	r.codings = append([]string{coding}, r.codings...)
+	r.encoders[coding] = fn
+}
+
+// offers lists the codings for negotiate.Encoding, with identity last.
+func (r *Registry) offers() []string {
+	return append(append([]string{}, r.codings...), "identity")
+}
+
+type compressor struct {
+	registry *Registry
+	minSize  int64
+}
+
+type Option func(*compressor)
+
+func WithRegistry(r *Registry) Option {
+	return func(c *compressor) {
+		c.registry = r
+	}
+}
+
+// WithMinSize overrides DefaultMinSize.
+func WithMinSize(n int64) Option {
+	return func(c *compressor) {
+		c.minSize = n
+	}
+}
+
+// Middleware compresses response bodies with the best coding from
+// Accept-Encoding. Responses that already have a Content-Encoding, carry an
+// already-compressed media type or text/event-stream, are smaller than the
+// minimum size, are partial or have no body are sent unchanged. Chunked
+// bodies are flushed through the encoder chunk by chunk. A strong ETag is
+// made weak on compressed responses.
+func Middleware(next server.Handler, opts ...Option) server.Handler {
+	c := &compressor{registry: NewRegistry(), minSize: DefaultMinSize}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return func(w *response.Writer, req *request.Request) {
+		// HEAD is negotiated too so its headers match GET; the server
+		// suppresses the body
+		w.SetBodyEncoder(func(statusCode response.StatusCode, h headers.Headers) func(io.Writer) io.WriteCloser {
+			return c.encoder(req, statusCode, h)
+		})
+		next(w, req)
+	}
+}
+
+func (c *compressor) encoder(req *request.Request, statusCode response.StatusCode, h headers.Headers) func(io.Writer) io.WriteCloser {
+	if !compressible(statusCode, h, c.minSize) {
+		return nil
+	}
+	if !h.HasToken("Vary", "Accept-Encoding") && !h.HasToken("Vary", "*") {
+		h.Set("Vary", "Accept-Encoding")
+	}
+	if _, ok := req.Headers.Get("Accept-Encoding"); !ok {
+		// any coding would be allowed, but clients that do not ask for
+		// compression rarely expect it
+		return nil
+	}
+	coding, ok := negotiate.Encoding(req.Headers, c.registry.offers())
+	if !ok || coding == "identity" {
+		return nil
+	}
+	h.Set("Content-Encoding", coding)
+	if etag, ok := h.Get("ETag"); ok && !strings.HasPrefix(etag, "W/") {
+		// the coded bytes differ from the identity ones, so a strong tag
+		// would let If-Range mix ranges of the two
+		h.Override("ETag", "W/"+etag)
+	}
+	return c.registry.encoders[coding]
+}
+
+func compressible(statusCode response.StatusCode, h headers.Headers, minSize int64) bool {
+	if statusCode < 200 || statusCode == 204 || statusCode == response.StatusCodePartialContent || statusCode == response.StatusCodeNotModified {
+		return false
+	}
+	if _, ok := h.Get("Content-Encoding"); ok {
+		return false
+	}
+	if h.HasToken("Cache-Control", "no-transform") {
+		return false
+	}
+	if n, ok, err := h.ContentLength(); ok && (err != nil || n < minSize) {
+		return false
+	}
+	mediaType, _, _ := h.ContentType()
+	if mediaType == "text/event-stream" {
+		// events are small and proxies tend to buffer compressed streams
+		return false
+	}
+	return !compressedType(mediaType)
+}
+
+// compressedType reports whether mediaType is already compressed, so
+// compressing it again would only cost CPU.
+func compressedType(mediaType string) bool {
+	switch {
+	case mediaType == "image/svg+xml":
+		return false
+	case strings.HasPrefix(mediaType, "image/"),
+		strings.HasPrefix(mediaType, "video/"),
+		strings.HasPrefix(mediaType, "audio/"),
+		strings.HasPrefix(mediaType, "font/woff"):
+		return true
+	}
+	switch mediaType {
+	case "application/zip", "application/gzip", "application/x-gzip",
+		"application/x-bzip2", "application/x-xz", "application/zstd",
+		"application/x-7z-compressed", "application/pdf":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,234 @@
+package compress
+
+import (
+	"bufio"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/UUest/httpfromtcp/internal/request"
+	"github.com/UUest/httpfromtcp/internal/response"
+	"github.com/UUest/httpfromtcp/internal/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var largeBody = strings.Repeat("compress me please ", 100)
+
+func fixedHandler(contentType, body string) server.Handler {
+	return func(w *response.Writer, _ *request.Request) {
+		w.WriteStatusLine(response.StatusCodeSuccess)
+		h := response.GetDefaultHeaders(len(body))
+		h.Override("Content-Type", contentType)
+		h.Set("ETag", `"v1"`)
+		w.WriteHeaders(h)
+		w.WriteBody([]byte(body))
+	}
+}
+
+func serve(t *testing.T, h server.Handler, acceptEncoding string) (head, body string) {
+	t.Helper()
+	return serveMethod(t, h, "GET", acceptEncoding)
+}
+
+func serveMethod(t *testing.T, h server.Handler, method, acceptEncoding string) (head, body string) {
+	t.Helper()
+	raw := method + " / HTTP/1.1\r\nHost: localhost:42069\r\n"
+	if acceptEncoding != "" {
+		raw += "Accept-Encoding: " + acceptEncoding + "\r\n"
+	}
+	req, err := request.RequestFromReader(strings.NewReader(raw + "\r\n"))
+	require.NoError(t, err)
+	var out strings.Builder
+	w := response.NewWriter(&out)
+	w.SetDateHeader(false)
+	// as the server does for HEAD
+	w.SuppressBody(method == "HEAD")
+	h(w, req)
+	head, body, _ = strings.Cut(out.String(), "\r\n\r\n")
+	return head + "\r\n", body
+}
+
+// dechunk decodes a chunked body without trailers.
+func dechunk(t *testing.T, body string) string {
+	t.Helper()
+	var out strings.Builder
+	for {
+		sizeLine, rest, ok := strings.Cut(body, "\r\n")
+		require.True(t, ok)
+		size, err := strconv.ParseInt(sizeLine, 16, 64)
+		require.NoError(t, err)
+		if size == 0 {
+			require.Equal(t, "\r\n", rest)
+			return out.String()
+		}
+		out.WriteString(rest[:size])
+		body = rest[size+2:]
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	h := Middleware(fixedHandler("application/json", largeBody))
+
+	// Test: gzip is chosen and the body is chunked
+	head, body := serve(t, h, "gzip, deflate;q=0.5")
+	assert.Contains(t, head, "content-encoding: gzip\r\n")
+	assert.Contains(t, head, "transfer-encoding: chunked\r\n")
+	assert.Contains(t, head, "vary: Accept-Encoding\r\n")
+	assert.Contains(t, head, "etag: W/\"v1\"\r\n")
+	assert.NotContains(t, head, "content-length")
+	zr, err := gzip.NewReader(strings.NewReader(dechunk(t, body)))
+	require.NoError(t, err)
+	b, err := io.ReadAll(zr)
+	require.NoError(t, err)
+	assert.Equal(t, largeBody, string(b))
+
+	// Test: HEAD gets the same headers as GET and no body
+	getHead, _ := serve(t, h, "gzip")
+	head, body = serveMethod(t, h, "HEAD", "gzip")
+	assert.ElementsMatch(t, strings.Split(getHead, "\r\n"), strings.Split(head, "\r\n"))
+	assert.Contains(t, head, "content-encoding: gzip\r\n")
+	assert.Empty(t, body)
+
+	// Test: deflate means the zlib format
+	head, body = serve(t, h, "deflate")
+	assert.Contains(t, head, "content-encoding: deflate\r\n")
+	zlr, err := zlib.NewReader(strings.NewReader(dechunk(t, body)))
+	require.NoError(t, err)
+	b, err = io.ReadAll(zlr)
+	require.NoError(t, err)
+	assert.Equal(t, largeBody, string(b))
+
+	// Test: No Accept-Encoding still varies
+	head, body = serve(t, h, "")
+	assert.NotContains(t, head, "content-encoding")
+	assert.Contains(t, head, "vary: Accept-Encoding\r\n")
+	assert.Contains(t, head, "etag: \"v1\"\r\n")
+	assert.Equal(t, largeBody, body)
+
+	// Test: Small bodies and compressed types are left alone
+	head, _ = serve(t, Middleware(fixedHandler("application/json", "{}")), "gzip")
+	assert.NotContains(t, head, "content-encoding")
+	assert.NotContains(t, head, "vary")
+	head, _ = serve(t, Middleware(fixedHandler("video/mp4", largeBody)), "gzip")
+	assert.NotContains(t, head, "content-encoding")
+	head, _ = serve(t, Middleware(fixedHandler("text/event-stream", largeBody)), "gzip")
+	assert.NotContains(t, head, "content-encoding")
+	head, _ = serve(t, Middleware(fixedHandler("application/json", "{}"), WithMinSize(0)), "gzip")
+	assert.Contains(t, head, "content-encoding: gzip\r\n")
+}
+
+func TestRegistry(t *testing.T) {
+	// Test: A registered coding is preferred on ties
+	r := NewRegistry()
+	r.Register("upper", func(w io.Writer) io.WriteCloser {
+		return upperWriter{w}
+	})
+	h := Middleware(fixedHandler("text/plain", largeBody), WithRegistry(r))
+	head, body := serve(t, h, "gzip, upper")
+	assert.Contains(t, head, "content-encoding: upper\r\n")
+	assert.Equal(t, strings.ToUpper(largeBody), dechunk(t, body))
+
+	// Test: Unregistered codings fall back to identity
+	head, body = serve(t, h, "br")
+	assert.NotContains(t, head, "content-encoding")
+	assert.Equal(t, largeBody, body)
+}
+
+type upperWriter struct {
+	w io.Writer
+}
+
+func (u upperWriter) Write(p []byte) (int, error) {
+	return u.w.Write([]byte(strings.ToUpper(string(p))))
+}
+
+func (upperWriter) Close() error {
+	return nil
+}
+
+// chunkedBody decodes a chunked body from r as it arrives.
+type chunkedBody struct {
+	r       *bufio.Reader
+	pending int64
+	done    bool
+}
+
+func (c *chunkedBody) Read(p []byte) (int, error) {
+	if c.done {
+		return 0, io.EOF
+	}
+	if c.pending == 0 {
+		line, err := c.r.ReadString('\n')
+		if err != nil {
+			return 0, err
+		}
+		size, err := strconv.ParseInt(strings.TrimSpace(line), 16, 64)
+		if err != nil {
+			return 0, err
+		}
+		if size == 0 {
+			c.done = true
+			return 0, io.EOF
+		}
+		c.pending = size
+	}
+	n, err := c.r.Read(p[:min(int64(len(p)), c.pending)])
+	c.pending -= int64(n)
+	if c.pending == 0 && err == nil {
+		_, err = c.r.Discard(2)
+	}
+	return n, err
+}
+
+func TestStreamedBodyIsFlushed(t *testing.T) {
+	// Test: Each chunk can be decoded before the handler writes the next
+	first, second := strings.Repeat("a", 2000), strings.Repeat("b", 2000)
+	proceed := make(chan struct{})
+	h := Middleware(func(w *response.Writer, _ *request.Request) {
+		w.WriteStatusLine(response.StatusCodeSuccess)
+		h := response.GetDefaultHeaders(0)
+		h.Remove("Content-Length")
+		h.Override("Content-Type", "application/json")
+		h.Override("Transfer-Encoding", "chunked")
+		w.WriteHeaders(h)
+		w.WriteChunkedBody([]byte(first))
+		<-proceed
+		w.WriteChunkedBody([]byte(second))
+		w.WriteChunkedBodyDone()
+		w.WriteTrailers(nil)
+	})
+	req, err := request.RequestFromReader(strings.NewReader("GET / HTTP/1.1\r\nHost: localhost:42069\r\nAccept-Encoding: gzip\r\n\r\n"))
+	require.NoError(t, err)
+	pr, pw := io.Pipe()
+	go func() {
+		h(response.NewWriter(pw), req)
+		pw.Close()
+	}()
+
+	br := bufio.NewReader(pr)
+	var head strings.Builder
+	for {
+		line, err := br.ReadString('\n')
+		require.NoError(t, err)
+		head.WriteString(line)
+		if line == "\r\n" {
+			break
+		}
+	}
+	assert.Contains(t, head.String(), "content-encoding: gzip\r\n")
+	zr, err := gzip.NewReader(&chunkedBody{r: br})
+	require.NoError(t, err)
+	b := make([]byte, len(first))
+	_, err = io.ReadFull(zr, b)
+	require.NoError(t, err)
+	assert.Equal(t, first, string(b))
+
+	close(proceed)
+	rest, err := io.ReadAll(zr)
+	require.NoError(t, err)
+	assert.Equal(t, second, string(rest))
+}
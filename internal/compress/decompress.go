@@ -0,0 +1,110 @@
+package compress
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/UUest/httpfromtcp/internal/jsonapi"
+	"github.com/UUest/httpfromtcp/internal/request"
+	"github.com/UUest/httpfromtcp/internal/response"
+	"github.com/UUest/httpfromtcp/internal/server"
+)
+
+// DefaultMaxDecompressedSize caps a decoded request body when
+// DecompressRequest is given a limit of zero.
+const DefaultMaxDecompressedSize = 10 << 20
+
+var (
+	ErrUnsupportedEncoding  = errors.New("unsupported Content-Encoding")
+	ErrDecompressedTooLarge = errors.New("decompressed body too large")
+)
+
+// decoders for request bodies; x-gzip is an alias from RFC 9110 section 8.4.1.3
+var decoders = map[string]func(io.Reader) (io.Reader, error){
+	"gzip": func(r io.Reader) (io.Reader, error) {
+		return gzip.NewReader(r)
+	},
+	"x-gzip": func(r io.Reader) (io.Reader, error) {
+		return gzip.NewReader(r)
+	},
+	"deflate": func(r io.Reader) (io.Reader, error) {
+		return zlib.NewReader(r)
+	},
+}
+
+// DecompressRequest decodes gzip and deflate request bodies before calling
+// next, so handlers see the original bytes in req.Body. Content-Encoding is
+// removed and Content-Length updated. Bodies that decode to more than
+// maxSize bytes are answered with 413, unknown codings with 415 and corrupt
+// data with 400.
+func DecompressRequest(next server.Handler, maxSize int64) server.Handler {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxDecompressedSize
+	}
+	return func(w *response.Writer, req *request.Request) {
+		codings := req.Headers.List("Content-Encoding")
+		if len(codings) == 0 {
+			next(w, req)
+			return
+		}
+		body, err := req.ReadBody()
+		if err != nil {
+			jsonapi.WriteProblem(w, jsonapi.NewProblem(response.StatusCodeBadRequest, err.Error()))
+			return
+		}
+		body, err = decodeBody(body, codings, maxSize)
+		if err != nil {
+			statusCode := response.StatusCodeBadRequest
+			switch {
+			case errors.Is(err, ErrUnsupportedEncoding):
+				statusCode = response.StatusCodeUnsupportedMediaType
+			case errors.Is(err, ErrDecompressedTooLarge):
+				statusCode = response.StatusCodeContentTooLarge
+			}
+			p := jsonapi.NewProblem(statusCode, err.Error())
+			if statusCode == response.StatusCodeUnsupportedMediaType {
+				p.Extensions = map[string]any{"supported": []string{"gzip", "deflate"}}
+			}
+			jsonapi.WriteProblem(w, p)
+			return
+		}
+		req.Body = body
+		req.Headers.Remove("Content-Encoding")
+		req.Headers.Override("Content-Length", strconv.Itoa(len(body)))
+		next(w, req)
+	}
+}
+
+// decodeBody undoes codings, which are listed in the order they were applied.
+func decodeBody(body []byte, codings []string, maxSize int64) ([]byte, error) {
+	for i := len(codings) - 1; i >= 0; i-- {
+		coding := strings.ToLower(codings[i])
+		if coding == "identity" {
+			continue
+		}
+		decoder, ok := decoders[coding]
+		if !ok {
+			return nil, fmt.Errorf("%w: %q", ErrUnsupportedEncoding, codings[i])
+		}
+		r, err := decoder(bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s body: %v", coding, err)
+		}
+		// read one byte past the limit to tell "exactly maxSize" from "more"
+		decoded, err := io.ReadAll(io.LimitReader(r, maxSize+1))
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s body: %v", coding, err)
+		}
+		if int64(len(decoded)) > maxSize {
+			return nil, fmt.Errorf("%w: limit %d bytes", ErrDecompressedTooLarge, maxSize)
+		}
+		body = decoded
+	}
+	return body, nil
+}
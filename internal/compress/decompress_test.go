@@ -0,0 +1,91 @@
+package compress
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/UUest/httpfromtcp/internal/request"
+	"github.com/UUest/httpfromtcp/internal/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func gzipped(t *testing.T, s string) string {
+	t.Helper()
+	var b bytes.Buffer
+	zw := gzip.NewWriter(&b)
+	_, err := zw.Write([]byte(s))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	return b.String()
+}
+
+func zlibbed(t *testing.T, s string) string {
+	t.Helper()
+	var b bytes.Buffer
+	zw := zlib.NewWriter(&b)
+	_, err := zw.Write([]byte(s))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	return b.String()
+}
+
+func upload(t *testing.T, maxSize int64, contentEncoding, body string) (out string, got *request.Request) {
+	t.Helper()
+	raw := "POST /batch HTTP/1.1\r\nHost: localhost:42069\r\n"
+	if contentEncoding != "" {
+		raw += "Content-Encoding: " + contentEncoding + "\r\n"
+	}
+	raw += fmt.Sprintf("Content-Length: %d\r\n\r\n%s", len(body), body)
+	req, err := request.HeadersFromReader(strings.NewReader(raw))
+	require.NoError(t, err)
+	var b strings.Builder
+	h := DecompressRequest(func(w *response.Writer, req *request.Request) {
+		got = req
+	}, maxSize)
+	h(response.NewWriter(&b), req)
+	return b.String(), got
+}
+
+func TestDecompressRequest(t *testing.T) {
+	// Test: gzip body is decoded for the handler
+	out, req := upload(t, 0, "gzip", gzipped(t, "hello batch"))
+	assert.Empty(t, out)
+	require.NotNil(t, req)
+	assert.Equal(t, "hello batch", string(req.Body))
+	_, ok := req.Headers.Get("Content-Encoding")
+	assert.False(t, ok)
+	contentLength, _ := req.Headers.Get("Content-Length")
+	assert.Equal(t, "11", contentLength)
+
+	// Test: Stacked codings are undone in reverse order
+	_, req = upload(t, 0, "deflate, gzip", gzipped(t, zlibbed(t, "two layers")))
+	require.NotNil(t, req)
+	assert.Equal(t, "two layers", string(req.Body))
+
+	// Test: Unencoded bodies pass through
+	_, req = upload(t, 0, "", "plain")
+	require.NotNil(t, req)
+	body, err := req.ReadBody()
+	require.NoError(t, err)
+	assert.Equal(t, "plain", string(body))
+
+	// Test: Decompression bomb
+	out, req = upload(t, 1000, "gzip", gzipped(t, strings.Repeat("a", 1001)))
+	assert.Nil(t, req)
+	assert.True(t, strings.HasPrefix(out, "HTTP/1.1 413 Content Too Large\r\n"))
+	_, req = upload(t, 1000, "gzip", gzipped(t, strings.Repeat("a", 1000)))
+	assert.NotNil(t, req)
+
+	// Test: Unsupported coding and corrupt data
+	out, req = upload(t, 0, "br", "xyz")
+	assert.Nil(t, req)
+	assert.True(t, strings.HasPrefix(out, "HTTP/1.1 415 Unsupported Media Type\r\n"))
+	out, req = upload(t, 0, "gzip", "not gzip")
+	assert.Nil(t, req)
+	assert.True(t, strings.HasPrefix(out, "HTTP/1.1 400 Bad Request\r\n"))
+}
@@ -0,0 +1,142 @@
+package conditional
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/UUest/httpfromtcp/internal/headers"
+	"github.com/UUest/httpfromtcp/internal/request"
+	"github.com/UUest/httpfromtcp/internal/response"
+)
+
+// StrongETag returns a strong entity tag for content, which changes whenever
+// any byte of it does.
+func StrongETag(content []byte) string {
+	sum := sha256.Sum256(content)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// WeakETag returns a weak entity tag for content. Use it when equivalent
+// representations may differ in bytes, e.g. after compression.
+func WeakETag(content []byte) string {
+	return "W/" + StrongETag(content)
+}
+
+type Result int
+
+const (
+	// Proceed means the handler should send the full response.
+	Proceed Result = iota
+	NotModified
+	PreconditionFailed
+)
+
+// Evaluate checks the preconditions of req against the current validators of
+// the target resource, in the order of RFC 9110 section 13.2.2: If-Match,
+// then If-Unmodified-Since unless If-Match was sent, then If-None-Match, then
+// If-Modified-Since unless If-None-Match was sent. Either validator may be
+// empty. Date conditions are ignored if lastModified is zero or the date is
+// malformed.
+func Evaluate(req *request.Request, etag string, lastModified time.Time) Result {
+	method := req.RequestLine.Method
+	if _, ok := req.Headers.Get("If-Match"); ok {
+		if !matches(req.Headers, "If-Match", etag, strongMatch) {
+			return PreconditionFailed
+		}
+	} else if since, ok := validDate(req.Headers, "If-Unmodified-Since", lastModified); ok {
+		if lastModified.Truncate(time.Second).After(since) {
+			return PreconditionFailed
+		}
+	}
+
+	if _, ok := req.Headers.Get("If-None-Match"); ok {
+		if matches(req.Headers, "If-None-Match", etag, weakMatch) {
+			if method == "GET" || method == "HEAD" {
+				return NotModified
+			}
+			return PreconditionFailed
+		}
+	} else if method == "GET" || method == "HEAD" {
+		if since, ok := validDate(req.Headers, "If-Modified-Since", lastModified); ok {
+			if !lastModified.Truncate(time.Second).After(since) {
+				return NotModified
+			}
+		}
+	}
+	return Proceed
+}
+
+// Check evaluates the preconditions and, unless the handler should proceed,
+// answers with 304 or 412 right away. Handlers call it before generating the
+// body and return if it reports false.
+func Check(w *response.Writer, req *request.Request, etag string, lastModified time.Time) bool {
+	switch Evaluate(req, etag, lastModified) {
+	case NotModified:
+		w.WriteStatusLine(response.StatusCodeNotModified)
+		h := response.GetDefaultHeaders(0)
+		h.Remove("Content-Length")
+		h.Remove("Content-Type")
+		if etag != "" {
+			h.Set("ETag", etag)
+		}
+		if !lastModified.IsZero() {
+			h.Set("Last-Modified", headers.FormatTime(lastModified))
+		}
+		w.WriteHeaders(h)
+		return false
+	case PreconditionFailed:
+		w.WriteStatusLine(response.StatusCodePreconditionFailed)
+		body := []byte("412 Precondition Failed\n")
+		w.WriteHeaders(response.GetDefaultHeaders(len(body)))
+		w.WriteBody(body)
+		return false
+	}
+	return true
+}
+
+// IfRange reports whether a Range header should be honored: either there is
+// no If-Range, or it strongly matches etag or exactly equals lastModified.
+func IfRange(req *request.Request, etag string, lastModified time.Time) bool {
+	v, ok := req.Headers.Get("If-Range")
+	if !ok {
+		return true
+	}
+	if strings.HasPrefix(v, `"`) || strings.HasPrefix(v, "W/") {
+		return strongMatch(v, etag)
+	}
+	t, err := headers.ParseTime(v)
+	if err != nil || lastModified.IsZero() {
+		return false
+	}
+	return t.Equal(lastModified.Truncate(time.Second))
+}
+
+// matches reports whether the list header key contains "*" or a tag that
+// matches etag.
+func matches(h headers.Headers, key, etag string, match func(a, b string) bool) bool {
+	for _, candidate := range h.List(key) {
+		if candidate == "*" || match(candidate, etag) {
+			return true
+		}
+	}
+	return false
+}
+
+func strongMatch(a, b string) bool {
+	return a != "" && a == b && !strings.HasPrefix(a, "W/")
+}
+
+func weakMatch(a, b string) bool {
+	a, b = strings.TrimPrefix(a, "W/"), strings.TrimPrefix(b, "W/")
+	return a != "" && a == b
+}
+
+func validDate(h headers.Headers, key string, lastModified time.Time) (time.Time, bool) {
+	if lastModified.IsZero() {
+		return time.Time{}, false
+	}
+	t, ok, err := h.Time(key)
+	return t, ok && err == nil
+}
@@ -0,0 +1,103 @@
+package conditional
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/UUest/httpfromtcp/internal/headers"
+	"github.com/UUest/httpfromtcp/internal/request"
+	"github.com/UUest/httpfromtcp/internal/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var lastModified = time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+
+const etag = `"abc"`
+
+func newRequest(t *testing.T, method string, fields ...string) *request.Request {
+	t.Helper()
+	raw := method + " /doc HTTP/1.1\r\nHost: localhost:42069\r\n"
+	for _, f := range fields {
+		raw += f + "\r\n"
+	}
+	req, err := request.RequestFromReader(strings.NewReader(raw + "\r\n"))
+	require.NoError(t, err)
+	return req
+}
+
+func TestETags(t *testing.T) {
+	// Test: Tags are quoted, stable and weak on request
+	tag := StrongETag([]byte("hello"))
+	assert.Equal(t, tag, StrongETag([]byte("hello")))
+	assert.NotEqual(t, tag, StrongETag([]byte("hello!")))
+	assert.True(t, strings.HasPrefix(tag, `"`) && strings.HasSuffix(tag, `"`))
+	assert.Equal(t, "W/"+tag, WeakETag([]byte("hello")))
+}
+
+func TestEvaluate(t *testing.T) {
+	before := headers.FormatTime(lastModified.Add(-time.Hour))
+	at := headers.FormatTime(lastModified)
+
+	cases := []struct {
+		name   string
+		method string
+		fields []string
+		etag   string
+		want   Result
+	}{
+		{"no preconditions", "GET", nil, etag, Proceed},
+		{"If-Match matches", "PUT", []string{`If-Match: "x", "abc"`}, etag, Proceed},
+		{"If-Match star", "PUT", []string{"If-Match: *"}, etag, Proceed},
+		{"If-Match fails", "PUT", []string{`If-Match: "x"`}, etag, PreconditionFailed},
+		{"If-Match is strong", "PUT", []string{`If-Match: W/"abc"`}, etag, PreconditionFailed},
+		{"If-Match against weak tag", "PUT", []string{`If-Match: W/"abc"`}, `W/"abc"`, PreconditionFailed},
+		{"If-Unmodified-Since fails", "PUT", []string{"If-Unmodified-Since: " + before}, etag, PreconditionFailed},
+		{"If-Unmodified-Since passes", "PUT", []string{"If-Unmodified-Since: " + at}, etag, Proceed},
+		{"If-Match wins over If-Unmodified-Since", "PUT", []string{`If-Match: "abc"`, "If-Unmodified-Since: " + before}, etag, Proceed},
+		{"If-None-Match on GET", "GET", []string{`If-None-Match: W/"abc"`}, etag, NotModified},
+		{"If-None-Match on PUT", "PUT", []string{"If-None-Match: *"}, etag, PreconditionFailed},
+		{"If-None-Match differs", "GET", []string{`If-None-Match: "x"`}, etag, Proceed},
+		{"If-Modified-Since not modified", "GET", []string{"If-Modified-Since: " + at}, etag, NotModified},
+		{"If-Modified-Since modified", "GET", []string{"If-Modified-Since: " + before}, etag, Proceed},
+		{"If-Modified-Since ignored for POST", "POST", []string{"If-Modified-Since: " + at}, etag, Proceed},
+		{"If-None-Match wins over If-Modified-Since", "GET", []string{`If-None-Match: "x"`, "If-Modified-Since: " + at}, etag, Proceed},
+		{"If-Match is checked first", "GET", []string{`If-Match: "x"`, `If-None-Match: "abc"`}, etag, PreconditionFailed},
+		{"malformed date is ignored", "GET", []string{"If-Modified-Since: yesterday"}, etag, Proceed},
+	}
+	for _, tc := range cases {
+		req := newRequest(t, tc.method, tc.fields...)
+		assert.Equal(t, tc.want, Evaluate(req, tc.etag, lastModified), tc.name)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	// Test: 304 carries the validators but no body
+	var out strings.Builder
+	ok := Check(response.NewWriter(&out), newRequest(t, "GET", `If-None-Match: "abc"`), etag, lastModified)
+	assert.False(t, ok)
+	assert.True(t, strings.HasPrefix(out.String(), "HTTP/1.1 304 Not Modified\r\n"))
+	assert.Contains(t, out.String(), "etag: \"abc\"\r\n")
+	assert.Contains(t, out.String(), "last-modified: Fri, 01 Mar 2024 12:00:00 GMT\r\n")
+	assert.True(t, strings.HasSuffix(out.String(), "\r\n\r\n"))
+
+	// Test: 412
+	out.Reset()
+	ok = Check(response.NewWriter(&out), newRequest(t, "DELETE", `If-Match: "old"`), etag, lastModified)
+	assert.False(t, ok)
+	assert.True(t, strings.HasPrefix(out.String(), "HTTP/1.1 412 Precondition Failed\r\n"))
+
+	// Test: Nothing is written when the handler should proceed
+	out.Reset()
+	assert.True(t, Check(response.NewWriter(&out), newRequest(t, "GET"), etag, lastModified))
+	assert.Empty(t, out.String())
+}
+
+func TestIfRange(t *testing.T) {
+	assert.True(t, IfRange(newRequest(t, "GET"), etag, lastModified))
+	assert.True(t, IfRange(newRequest(t, "GET", `If-Range: "abc"`), etag, lastModified))
+	assert.False(t, IfRange(newRequest(t, "GET", `If-Range: W/"abc"`), `W/"abc"`, lastModified))
+	assert.True(t, IfRange(newRequest(t, "GET", "If-Range: "+headers.FormatTime(lastModified)), etag, lastModified))
+	assert.False(t, IfRange(newRequest(t, "GET", "If-Range: "+headers.FormatTime(lastModified.Add(time.Hour))), etag, lastModified))
+}
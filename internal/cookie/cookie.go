@@ -0,0 +1,147 @@
+package cookie
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/UUest/httpfromtcp/internal/headers"
+)
+
+type SameSite int
+
+const (
+	// SameSiteDefault omits the attribute and leaves the choice to the browser.
+	SameSiteDefault SameSite = iota
+	SameSiteLax
+	SameSiteStrict
+	SameSiteNone
+)
+
+var ErrInvalidCookie = errors.New("invalid cookie")
+
+// Cookie is a name/value pair from a Cookie header, or a cookie to send with
+// Set-Cookie. The attributes are only used for Set-Cookie.
+type Cookie struct {
+	Name  string
+	Value string
+
+	Path    string
+	Domain  string
+	Expires time.Time
+	// MaxAge in seconds. Zero omits the attribute, a negative value deletes
+	// the cookie by sending Max-Age=0.
+	MaxAge      int
+	Secure      bool
+	HttpOnly    bool
+	SameSite    SameSite
+	Partitioned bool
+}
+
+// Parse parses the value of a Cookie request header. Malformed pairs are
+// skipped. Pairs are separated by "; ", but commas are accepted too since
+// repeated Cookie headers get merged with ", ".
+func Parse(header string) []*Cookie {
+	var cookies []*Cookie
+	for _, pair := range strings.FieldsFunc(header, func(r rune) bool { return r == ';' || r == ',' }) {
+		name, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok || !headers.ValidFieldName(name) {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if len(value) > 1 && value[0] == '"' && value[len(value)-1] == '"' {
+			value = value[1 : len(value)-1]
+		}
+		if !validValue(value) {
+			continue
+		}
+		cookies = append(cookies, &Cookie{Name: name, Value: value})
+	}
+	return cookies
+}
+
+// Format returns the Set-Cookie field value for c.
+func (c *Cookie) Format() (string, error) {
+	if !headers.ValidFieldName(c.Name) {
+		return "", fmt.Errorf("%w: name %q", ErrInvalidCookie, c.Name)
+	}
+	if !validValue(c.Value) {
+		return "", fmt.Errorf("%w: value %q", ErrInvalidCookie, c.Value)
+	}
+	if !validAttribute(c.Path) {
+		return "", fmt.Errorf("%w: path %q", ErrInvalidCookie, c.Path)
+	}
+	if !validAttribute(c.Domain) || strings.ContainsAny(c.Domain, " ,") {
+		return "", fmt.Errorf("%w: domain %q", ErrInvalidCookie, c.Domain)
+	}
+	if c.SameSite == SameSiteNone && !c.Secure {
+		return "", fmt.Errorf("%w: SameSite=None requires Secure", ErrInvalidCookie)
+	}
+	if c.Partitioned && !c.Secure {
+		return "", fmt.Errorf("%w: Partitioned requires Secure", ErrInvalidCookie)
+	}
+
+	var b strings.Builder
+	b.WriteString(c.Name)
+	b.WriteString("=")
+	b.WriteString(c.Value)
+	if c.Path != "" {
+		b.WriteString("; Path=" + c.Path)
+	}
+	if c.Domain != "" {
+		b.WriteString("; Domain=" + strings.TrimPrefix(c.Domain, "."))
+	}
+	if !c.Expires.IsZero() {
+		b.WriteString("; Expires=" + headers.FormatTime(c.Expires))
+	}
+	switch {
+	case c.MaxAge > 0:
+		b.WriteString("; Max-Age=" + strconv.Itoa(c.MaxAge))
+	case c.MaxAge < 0:
+		b.WriteString("; Max-Age=0")
+	}
+	if c.Secure {
+		b.WriteString("; Secure")
+	}
+	if c.HttpOnly {
+		b.WriteString("; HttpOnly")
+	}
+	switch c.SameSite {
+	case SameSiteLax:
+		b.WriteString("; SameSite=Lax")
+	case SameSiteStrict:
+		b.WriteString("; SameSite=Strict")
+	case SameSiteNone:
+		b.WriteString("; SameSite=None")
+	}
+	if c.Partitioned {
+		b.WriteString("; Partitioned")
+	}
+	return b.String(), nil
+}
+
+// validValue checks for cookie-octets from RFC 6265 section 4.1.1: visible
+// ASCII except DQUOTE, comma, semicolon and backslash.
+func validValue(v string) bool {
+	for i := 0; i < len(v); i++ {
+		c := v[i]
+		if c <= ' ' || c >= 0x7f || c == '"' || c == ',' || c == ';' || c == '\\' {
+			return false
+		}
+	}
+	return true
+}
+
+// validAttribute checks attribute values like Path, which may contain any
+// visible character except ';'.
+func validAttribute(v string) bool {
+	for i := 0; i < len(v); i++ {
+		c := v[i]
+		if c < ' ' || c >= 0x7f || c == ';' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,63 @@
+package cookie
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	// Test: Several pairs, quoted value and merged headers
+	cookies := Parse(`session=abc123; theme="dark"; lang=en, tracking=1`)
+	require.Len(t, cookies, 4)
+	assert.Equal(t, "session", cookies[0].Name)
+	assert.Equal(t, "abc123", cookies[0].Value)
+	assert.Equal(t, "dark", cookies[1].Value)
+	assert.Equal(t, "lang", cookies[2].Name)
+	assert.Equal(t, "tracking", cookies[3].Name)
+
+	// Test: Malformed pairs are skipped
+	cookies = Parse(`novalue; bad name=x; ok=1; also=bad\value`)
+	require.Len(t, cookies, 1)
+	assert.Equal(t, "ok", cookies[0].Name)
+}
+
+func TestFormat(t *testing.T) {
+	// Test: All attributes
+	c := &Cookie{
+		Name:        "session",
+		Value:       "abc123",
+		Path:        "/",
+		Domain:      ".example.com",
+		Expires:     time.Date(2030, time.January, 2, 3, 4, 5, 0, time.UTC),
+		MaxAge:      3600,
+		Secure:      true,
+		HttpOnly:    true,
+		SameSite:    SameSiteNone,
+		Partitioned: true,
+	}
+	v, err := c.Format()
+	require.NoError(t, err)
+	assert.Equal(t, "session=abc123; Path=/; Domain=example.com; Expires=Wed, 02 Jan 2030 03:04:05 GMT; Max-Age=3600; Secure; HttpOnly; SameSite=None; Partitioned", v)
+
+	// Test: Deleting a cookie
+	v, err = (&Cookie{Name: "session", MaxAge: -1}).Format()
+	require.NoError(t, err)
+	assert.Equal(t, "session=; Max-Age=0", v)
+
+	// Test: Invalid value
+	_, err = (&Cookie{Name: "session", Value: "a;b"}).Format()
+	require.ErrorIs(t, err, ErrInvalidCookie)
+
+	// Test: Attribute injection through Path
+	_, err = (&Cookie{Name: "session", Value: "x", Path: "/; Domain=evil.com"}).Format()
+	require.ErrorIs(t, err, ErrInvalidCookie)
+
+	// Test: SameSite=None and Partitioned need Secure
+	_, err = (&Cookie{Name: "a", SameSite: SameSiteNone}).Format()
+	require.ErrorIs(t, err, ErrInvalidCookie)
+	_, err = (&Cookie{Name: "a", Partitioned: true}).Format()
+	require.ErrorIs(t, err, ErrInvalidCookie)
+}
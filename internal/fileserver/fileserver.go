@@ -0,0 +1,288 @@
+package fileserver
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/UUest/httpfromtcp/internal/conditional"
+	"github.com/UUest/httpfromtcp/internal/headers"
+	"github.com/UUest/httpfromtcp/internal/request"
+	"github.com/UUest/httpfromtcp/internal/response"
+	"github.com/UUest/httpfromtcp/internal/server"
+	"github.com/UUest/httpfromtcp/internal/urlpath"
+)
+
+const indexFile = "index.html"
+
+// sniffLen is how much of a file http.DetectContentType looks at.
+const sniffLen = 512
+
+type fileServer struct {
+	root     string
+	listDirs bool
+}
+
+type Option func(*fileServer)
+
+// WithDirectoryListing renders an HTML listing for directories that have no
+// index.html. Without it such directories are answered with 403.
+func WithDirectoryListing() Option {
+	return func(fs *fileServer) {
+		fs.listDirs = true
+	}
+}
+
+// New returns a handler serving the files below root, using req.Target.Path
+// as the path inside root.
+func New(root string, opts ...Option) server.Handler {
+	fs := &fileServer{root: root}
+	for _, opt := range opts {
+		opt(fs)
+	}
+	return fs.serve
+}
+
+func (fs *fileServer) serve(w *response.Writer, req *request.Request) {
+	if !checkMethod(w, req) {
+		return
+	}
+	name, err := urlpath.Join(fs.root, req.Target.Path)
+	if err != nil {
+		writeStatus(w, response.StatusCodeNotFound)
+		return
+	}
+	info, err := os.Stat(name)
+	if err != nil {
+		writeOpenError(w, err)
+		return
+	}
+	if !info.IsDir() {
+		if strings.HasSuffix(req.Target.Path, "/") {
+			writeStatus(w, response.StatusCodeNotFound)
+			return
+		}
+		ServeFile(w, req, name)
+		return
+	}
+
+	if !strings.HasSuffix(req.Target.Path, "/") {
+		location := req.Target.RawPath + "/"
+		if req.Target.RawQuery != "" {
+			location += "?" + req.Target.RawQuery
+		}
+		w.WriteStatusLine(response.StatusCodePermanentRedirect)
+		h := response.GetDefaultHeaders(0)
+		h.Set("Location", location)
+		w.WriteHeaders(h)
+		return
+	}
+	index := filepath.Join(name, indexFile)
+	if _, err := os.Stat(index); err == nil {
+		ServeFile(w, req, index)
+		return
+	}
+	if !fs.listDirs {
+		writeStatus(w, response.StatusCodeForbidden)
+		return
+	}
+	writeListing(w, req, name)
+}
+
+// ServeFile answers req with the contents of the named file.
+func ServeFile(w *response.Writer, req *request.Request, name string) {
+	if !checkMethod(w, req) {
+		return
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		writeOpenError(w, err)
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		writeOpenError(w, err)
+		return
+	}
+	if info.IsDir() {
+		writeStatus(w, response.StatusCodeForbidden)
+		return
+	}
+	ServeContent(w, req, info.Name(), info.ModTime(), f)
+}
+
+// ServeContent answers req with content. The Content-Type comes from the
+// extension of name, or from sniffing the first bytes if that is unknown. If
+// modtime is not zero it is sent as Last-Modified, together with an ETag
+// derived from modtime and size. Conditional requests are answered with 304
+// or 412 as decided by conditional.Check. Range requests are answered with
+// 206, using multipart/byteranges for more than one range, or 416 if no range
+// is satisfiable.
+func ServeContent(w *response.Writer, req *request.Request, name string, modtime time.Time, content io.ReadSeeker) {
+	size, err := content.Seek(0, io.SeekEnd)
+	if err != nil {
+		writeStatus(w, response.StatusCodeInternalServerError)
+		return
+	}
+	contentType, err := detectContentType(name, content)
+	if err != nil {
+		writeStatus(w, response.StatusCodeInternalServerError)
+		return
+	}
+
+	h := response.GetDefaultHeaders(int(size))
+	h.Override("Content-Type", contentType)
+	var etag string
+	if !modtime.IsZero() {
+		etag = fmt.Sprintf(`"%x-%x"`, modtime.UnixNano(), size)
+		h.Set("Last-Modified", headers.FormatTime(modtime))
+		h.Set("ETag", etag)
+	}
+	if !conditional.Check(w, req, etag, modtime) {
+		return
+	}
+
+	h.Set("Accept-Ranges", "bytes")
+	statusCode := response.StatusCodeSuccess
+	bodyRange := Range{Start: 0, Length: size}
+	var body io.Reader
+	rangeHeader, ok := req.Headers.Get("Range")
+	if ok && req.RequestLine.Method == "GET" && conditional.IfRange(req, etag, modtime) {
+		ranges, err := ParseRange(rangeHeader, size)
+		switch {
+		case errors.Is(err, ErrRangeNotSatisfiable):
+			w.WriteStatusLine(response.StatusCodeRangeNotSatisfiable)
+			h := response.GetDefaultHeaders(0)
+			h.Set("Content-Range", fmt.Sprintf("bytes */%d", size))
+			w.WriteHeaders(h)
+			return
+		case err != nil || sumLengths(ranges) > size:
+			// invalid Range headers are ignored, and so are overlapping
+			// ranges that would add up to more than the whole file
+		case len(ranges) == 1:
+			statusCode = response.StatusCodePartialContent
+			h.Override("Content-Length", strconv.FormatInt(ranges[0].Length, 10))
+			h.Set("Content-Range", ranges[0].ContentRange(size))
+			bodyRange = ranges[0]
+		default:
+			statusCode = response.StatusCodePartialContent
+			m := newMultipartRanges(ranges, contentType, size)
+			h.Override("Content-Length", strconv.FormatInt(m.length(), 10))
+			h.Override("Content-Type", m.contentType())
+			body = m.reader(content)
+		}
+	}
+
+	if body == nil {
+		_, err = content.Seek(bodyRange.Start, io.SeekStart)
+		if err != nil {
+			writeStatus(w, response.StatusCodeInternalServerError)
+			return
+		}
+		// a plain LimitedReader lets the Writer use sendfile for files
+		body = &io.LimitedReader{R: content, N: bodyRange.Length}
+	}
+
+	w.WriteStatusLine(statusCode)
+	w.WriteHeaders(h)
+	if req.RequestLine.Method == "HEAD" {
+		return
+	}
+	w.WriteBodyFrom(body)
+}
+
+func detectContentType(name string, content io.ReadSeeker) (string, error) {
+	if contentType := mime.TypeByExtension(filepath.Ext(name)); contentType != "" {
+		return contentType, nil
+	}
+	_, err := content.Seek(0, io.SeekStart)
+	if err != nil {
+		return "", err
+	}
+	buf := make([]byte, sniffLen)
+	n, err := io.ReadFull(content, buf)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return "", err
+	}
+	return http.DetectContentType(buf[:n]), nil
+}
+
+func checkMethod(w *response.Writer, req *request.Request) bool {
+	if req.RequestLine.Method == "GET" || req.RequestLine.Method == "HEAD" {
+		return true
+	}
+	w.WriteStatusLine(response.StatusCodeMethodNotAllowed)
+	body := []byte(response.StatusText(response.StatusCodeMethodNotAllowed) + "\n")
+	h := response.GetDefaultHeaders(len(body))
+	h.Set("Allow", "GET, HEAD")
+	w.WriteHeaders(h)
+	w.WriteBody(body)
+	return false
+}
+
+func writeOpenError(w *response.Writer, err error) {
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		writeStatus(w, response.StatusCodeNotFound)
+	case errors.Is(err, os.ErrPermission):
+		writeStatus(w, response.StatusCodeForbidden)
+	default:
+		writeStatus(w, response.StatusCodeInternalServerError)
+	}
+}
+
+func writeStatus(w *response.Writer, statusCode response.StatusCode) {
+	w.WriteStatusLine(statusCode)
+	body := []byte(strconv.Itoa(int(statusCode)) + " " + response.StatusText(statusCode) + "\n")
+	w.WriteHeaders(response.GetDefaultHeaders(len(body)))
+	w.WriteBody(body)
+}
+
+func writeListing(w *response.Writer, req *request.Request, dir string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		writeOpenError(w, err)
+		return
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+
+	var b strings.Builder
+	title := html.EscapeString(req.Target.Path)
+	fmt.Fprintf(&b, "<html>\n<head>\n<title>Index of %s</title>\n</head>\n<body>\n<h1>Index of %s</h1>\n<ul>\n", title, title)
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() {
+			name += "/"
+		}
+		href := (&url.URL{Path: name}).EscapedPath()
+		if strings.Contains(name, ":") {
+			// keep "a:b" from being read as a scheme
+			href = "./" + href
+		}
+		fmt.Fprintf(&b, "<li><a href=\"%s\">%s</a></li>\n", html.EscapeString(href), html.EscapeString(name))
+	}
+	b.WriteString("</ul>\n</body>\n</html>\n")
+
+	body := []byte(b.String())
+	w.WriteStatusLine(response.StatusCodeSuccess)
+	h := response.GetDefaultHeaders(len(body))
+	h.Override("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeaders(h)
+	if req.RequestLine.Method == "HEAD" {
+		return
+	}
+	w.WriteBody(body)
+}
@@ -0,0 +1,128 @@
+package fileserver
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/UUest/httpfromtcp/internal/headers"
+	"github.com/UUest/httpfromtcp/internal/request"
+	"github.com/UUest/httpfromtcp/internal/response"
+	"github.com/UUest/httpfromtcp/internal/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testModTime = time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+
+func newTestRoot(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	files := map[string]string{
+		"hello.txt":       "hello, world\n",
+		"noext":           "<html><body>sniffed</body></html>",
+		"site/index.html": "<h1>index</h1>",
+		"files/a.txt":     "a",
+		"files/<b>.txt":   "b",
+		"files/sub/c.txt": "c",
+	}
+	for name, content := range files {
+		full := filepath.Join(root, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(full), 0o755))
+		require.NoError(t, os.WriteFile(full, []byte(content), 0o644))
+		require.NoError(t, os.Chtimes(full, testModTime, testModTime))
+	}
+	return root
+}
+
+func serve(t *testing.T, h server.Handler, raw string) string {
+	t.Helper()
+	req, err := request.RequestFromReader(strings.NewReader(raw))
+	require.NoError(t, err)
+	var out strings.Builder
+	h(response.NewWriter(&out), req)
+	return out.String()
+}
+
+func get(path string, extra ...string) string {
+	return "GET " + path + " HTTP/1.1\r\nHost: localhost:42069\r\n" + strings.Join(extra, "") + "\r\n"
+}
+
+func TestServeFiles(t *testing.T) {
+	h := New(newTestRoot(t))
+
+	// Test: Regular file with validators
+	out := serve(t, h, get("/hello.txt"))
+	assert.True(t, strings.HasPrefix(out, "HTTP/1.1 200 OK\r\n"))
+	assert.Contains(t, out, "content-type: text/plain; charset=utf-8\r\n")
+	assert.Contains(t, out, "content-length: 13\r\n")
+	assert.Contains(t, out, "last-modified: Fri, 01 Mar 2024 12:00:00 GMT\r\n")
+	assert.Contains(t, out, "etag: \"")
+	assert.True(t, strings.HasSuffix(out, "\r\n\r\nhello, world\n"))
+
+	// Test: Content-Type is sniffed without a known extension
+	out = serve(t, h, get("/noext"))
+	assert.Contains(t, out, "content-type: text/html; charset=utf-8\r\n")
+
+	// Test: HEAD has headers but no body
+	out = serve(t, h, "HEAD /hello.txt HTTP/1.1\r\nHost: localhost:42069\r\n\r\n")
+	assert.Contains(t, out, "content-length: 13\r\n")
+	assert.True(t, strings.HasSuffix(out, "\r\n\r\n"))
+
+	// Test: Missing files, escaping the root and other methods
+	assert.True(t, strings.HasPrefix(serve(t, h, get("/missing.txt")), "HTTP/1.1 404 Not Found\r\n"))
+	assert.True(t, strings.HasPrefix(serve(t, h, get("/hello.txt/")), "HTTP/1.1 404 Not Found\r\n"))
+	assert.True(t, strings.HasPrefix(serve(t, h, get("/../../etc/passwd")), "HTTP/1.1 404 Not Found\r\n"))
+	out = serve(t, h, "DELETE /hello.txt HTTP/1.1\r\nHost: localhost:42069\r\n\r\n")
+	assert.True(t, strings.HasPrefix(out, "HTTP/1.1 405 Method Not Allowed\r\n"))
+	assert.Contains(t, out, "allow: GET, HEAD\r\n")
+}
+
+func TestConditionalRequests(t *testing.T) {
+	h := New(newTestRoot(t))
+	out := serve(t, h, get("/hello.txt"))
+	_, rest, _ := strings.Cut(out, "etag: ")
+	etag, _, _ := strings.Cut(rest, "\r\n")
+
+	// Test: Matching If-None-Match, also as a weak tag in a list
+	out = serve(t, h, get("/hello.txt", "If-None-Match: "+etag+"\r\n"))
+	assert.True(t, strings.HasPrefix(out, "HTTP/1.1 304 Not Modified\r\n"))
+	assert.NotContains(t, out, "content-length")
+	assert.Contains(t, out, "etag: "+etag+"\r\n")
+	out = serve(t, h, get("/hello.txt", "If-None-Match: \"other\", W/"+etag+"\r\n"))
+	assert.True(t, strings.HasPrefix(out, "HTTP/1.1 304 Not Modified\r\n"))
+
+	// Test: Different ETag wins over a matching If-Modified-Since
+	out = serve(t, h, get("/hello.txt", "If-None-Match: \"other\"\r\n", "If-Modified-Since: "+headers.FormatTime(testModTime)+"\r\n"))
+	assert.True(t, strings.HasPrefix(out, "HTTP/1.1 200 OK\r\n"))
+
+	// Test: If-Modified-Since
+	out = serve(t, h, get("/hello.txt", "If-Modified-Since: "+headers.FormatTime(testModTime)+"\r\n"))
+	assert.True(t, strings.HasPrefix(out, "HTTP/1.1 304 Not Modified\r\n"))
+	out = serve(t, h, get("/hello.txt", "If-Modified-Since: "+headers.FormatTime(testModTime.Add(-time.Hour))+"\r\n"))
+	assert.True(t, strings.HasPrefix(out, "HTTP/1.1 200 OK\r\n"))
+}
+
+func TestDirectories(t *testing.T) {
+	root := newTestRoot(t)
+
+	// Test: Redirect to the trailing slash
+	out := serve(t, New(root), get("/site?x=1"))
+	assert.True(t, strings.HasPrefix(out, "HTTP/1.1 308 Permanent Redirect\r\n"))
+	assert.Contains(t, out, "location: /site/?x=1\r\n")
+
+	// Test: Index file
+	out = serve(t, New(root), get("/site/"))
+	assert.True(t, strings.HasSuffix(out, "<h1>index</h1>"))
+
+	// Test: No listing without the option
+	out = serve(t, New(root), get("/files/"))
+	assert.True(t, strings.HasPrefix(out, "HTTP/1.1 403 Forbidden\r\n"))
+
+	// Test: Listing is sorted and escaped
+	out = serve(t, New(root, WithDirectoryListing()), get("/files/"))
+	assert.True(t, strings.HasPrefix(out, "HTTP/1.1 200 OK\r\n"))
+	assert.Contains(t, out, "<li><a href=\"%3Cb%3E.txt\">&lt;b&gt;.txt</a></li>\n<li><a href=\"a.txt\">a.txt</a></li>\n<li><a href=\"sub/\">sub/</a></li>\n")
+}
@@ -0,0 +1,159 @@
+package fileserver
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/UUest/httpfromtcp/internal/headers"
+)
+
+var (
+	ErrInvalidRange        = errors.New("invalid Range header")
+	ErrRangeNotSatisfiable = errors.New("no satisfiable range")
+)
+
+// Range is a byte range resolved against the size of a representation.
+type Range struct {
+	Start  int64
+	Length int64
+}
+
+// ContentRange formats r as a Content-Range value for a representation of
+// the given size.
+func (r Range) ContentRange(size int64) string {
+	return fmt.Sprintf("bytes %d-%d/%d", r.Start, r.Start+r.Length-1, size)
+}
+
+// ParseRange parses a Range header value such as "bytes=0-99,-500" for a
+// representation of the given size. Ranges that start past the end are
+// dropped; if none are left ErrRangeNotSatisfiable is returned. Malformed
+// values and units other than bytes return ErrInvalidRange.
+func ParseRange(v string, size int64) ([]Range, error) {
+	unit, set, ok := strings.Cut(v, "=")
+	if !ok || !strings.EqualFold(strings.TrimSpace(unit), "bytes") {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidRange, v)
+	}
+	var ranges []Range
+	specs := 0
+	for _, spec := range strings.Split(set, ",") {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+		specs++
+		first, last, ok := strings.Cut(spec, "-")
+		if !ok {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidRange, spec)
+		}
+		if first == "" {
+			// suffix range: the last n bytes
+			n, err := headers.ParseDecimal(last)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %q", ErrInvalidRange, spec)
+			}
+			if n == 0 || size == 0 {
+				continue
+			}
+			n = min(n, size)
+			ranges = append(ranges, Range{Start: size - n, Length: n})
+			continue
+		}
+		start, err := headers.ParseDecimal(first)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidRange, spec)
+		}
+		end := size - 1
+		if last != "" {
+			end, err = headers.ParseDecimal(last)
+			if err != nil || end < start {
+				return nil, fmt.Errorf("%w: %q", ErrInvalidRange, spec)
+			}
+			end = min(end, size-1)
+		}
+		if start >= size {
+			continue
+		}
+		ranges = append(ranges, Range{Start: start, Length: end - start + 1})
+	}
+	if specs == 0 {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidRange, v)
+	}
+	if len(ranges) == 0 {
+		return nil, ErrRangeNotSatisfiable
+	}
+	return ranges, nil
+}
+
+// multipartRanges lays out a multipart/byteranges body. Its parts are read
+// from content one after the other, seeking to each range as it is reached.
+type multipartRanges struct {
+	boundary string
+	headers  []string
+	ranges   []Range
+}
+
+func newMultipartRanges(ranges []Range, contentType string, size int64) *multipartRanges {
+	var b [16]byte
+	rand.Read(b[:])
+	m := &multipartRanges{boundary: hex.EncodeToString(b[:]), ranges: ranges}
+	for _, r := range ranges {
+		m.headers = append(m.headers, fmt.Sprintf("\r\n--%s\r\nContent-Type: %s\r\nContent-Range: %s\r\n\r\n", m.boundary, contentType, r.ContentRange(size)))
+	}
+	return m
+}
+
+func (m *multipartRanges) contentType() string {
+	return "multipart/byteranges; boundary=" + m.boundary
+}
+
+func (m *multipartRanges) closing() string {
+	return "\r\n--" + m.boundary + "--\r\n"
+}
+
+func (m *multipartRanges) length() int64 {
+	n := int64(len(m.closing()))
+	for i, r := range m.ranges {
+		n += int64(len(m.headers[i])) + r.Length
+	}
+	return n
+}
+
+func (m *multipartRanges) reader(content io.ReadSeeker) io.Reader {
+	var readers []io.Reader
+	for i, r := range m.ranges {
+		readers = append(readers, strings.NewReader(m.headers[i]), &rangeReader{content: content, r: r})
+	}
+	readers = append(readers, strings.NewReader(m.closing()))
+	return io.MultiReader(readers...)
+}
+
+// rangeReader seeks to its range on the first Read, so several of them can
+// share one io.ReadSeeker as long as they are read in order.
+type rangeReader struct {
+	content io.ReadSeeker
+	r       Range
+	body    io.Reader
+}
+
+func (rr *rangeReader) Read(p []byte) (int, error) {
+	if rr.body == nil {
+		_, err := rr.content.Seek(rr.r.Start, io.SeekStart)
+		if err != nil {
+			return 0, err
+		}
+		rr.body = io.LimitReader(rr.content, rr.r.Length)
+	}
+	return rr.body.Read(p)
+}
+
+func sumLengths(ranges []Range) int64 {
+	var n int64
+	for _, r := range ranges {
+		n += r.Length
+	}
+	return n
+}
@@ -0,0 +1,103 @@
+package fileserver
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/UUest/httpfromtcp/internal/headers"
+	"github.com/UUest/httpfromtcp/internal/request"
+	"github.com/UUest/httpfromtcp/internal/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRange(t *testing.T) {
+	// Test: Single, open-ended and suffix ranges
+	ranges, err := ParseRange("bytes=0-4", 10)
+	require.NoError(t, err)
+	assert.Equal(t, []Range{{Start: 0, Length: 5}}, ranges)
+	ranges, err = ParseRange("bytes=7-", 10)
+	require.NoError(t, err)
+	assert.Equal(t, []Range{{Start: 7, Length: 3}}, ranges)
+	ranges, err = ParseRange("bytes=-3", 10)
+	require.NoError(t, err)
+	assert.Equal(t, []Range{{Start: 7, Length: 3}}, ranges)
+
+	// Test: Multiple ranges, clamped to the size
+	ranges, err = ParseRange("bytes=0-1, 5-100 ,-20", 10)
+	require.NoError(t, err)
+	assert.Equal(t, []Range{{Start: 0, Length: 2}, {Start: 5, Length: 5}, {Start: 0, Length: 10}}, ranges)
+
+	// Test: Unsatisfiable ranges are dropped
+	ranges, err = ParseRange("bytes=20-30,2-3", 10)
+	require.NoError(t, err)
+	assert.Equal(t, []Range{{Start: 2, Length: 2}}, ranges)
+	_, err = ParseRange("bytes=10-", 10)
+	require.ErrorIs(t, err, ErrRangeNotSatisfiable)
+	_, err = ParseRange("bytes=-0", 10)
+	require.ErrorIs(t, err, ErrRangeNotSatisfiable)
+
+	// Test: Malformed values
+	for _, v := range []string{"0-4", "items=0-4", "bytes=", "bytes=4-2", "bytes=a-b", "bytes=+1-2", "bytes=1"} {
+		_, err = ParseRange(v, 10)
+		require.ErrorIs(t, err, ErrInvalidRange, v)
+	}
+}
+
+func serveContent(t *testing.T, extra ...string) string {
+	t.Helper()
+	raw := "GET /digits.txt HTTP/1.1\r\nHost: localhost:42069\r\n" + strings.Join(extra, "") + "\r\n"
+	req, err := request.RequestFromReader(strings.NewReader(raw))
+	require.NoError(t, err)
+	var out strings.Builder
+	ServeContent(response.NewWriter(&out), req, "digits.txt", testModTime, strings.NewReader("0123456789"))
+	return out.String()
+}
+
+func TestServeContentRanges(t *testing.T) {
+	// Test: Full content advertises range support
+	out := serveContent(t)
+	assert.True(t, strings.HasPrefix(out, "HTTP/1.1 200 OK\r\n"))
+	assert.Contains(t, out, "accept-ranges: bytes\r\n")
+
+	// Test: Single range
+	out = serveContent(t, "Range: bytes=2-5\r\n")
+	assert.True(t, strings.HasPrefix(out, "HTTP/1.1 206 Partial Content\r\n"))
+	assert.Contains(t, out, "content-range: bytes 2-5/10\r\n")
+	assert.Contains(t, out, "content-length: 4\r\n")
+	assert.True(t, strings.HasSuffix(out, "\r\n\r\n2345"))
+
+	// Test: Multiple ranges
+	out = serveContent(t, "Range: bytes=0-1,-2\r\n")
+	assert.True(t, strings.HasPrefix(out, "HTTP/1.1 206 Partial Content\r\n"))
+	_, rest, _ := strings.Cut(out, "content-type: multipart/byteranges; boundary=")
+	boundary, _, _ := strings.Cut(rest, "\r\n")
+	_, body, _ := strings.Cut(out, "\r\n\r\n")
+	assert.Equal(t, "\r\n--"+boundary+"\r\nContent-Type: text/plain; charset=utf-8\r\nContent-Range: bytes 0-1/10\r\n\r\n01"+
+		"\r\n--"+boundary+"\r\nContent-Type: text/plain; charset=utf-8\r\nContent-Range: bytes 8-9/10\r\n\r\n89"+
+		"\r\n--"+boundary+"--\r\n", body)
+	assert.Contains(t, out, "content-length: "+strconv.Itoa(len(body))+"\r\n")
+
+	// Test: Unsatisfiable
+	out = serveContent(t, "Range: bytes=50-60\r\n")
+	assert.True(t, strings.HasPrefix(out, "HTTP/1.1 416 Range Not Satisfiable\r\n"))
+	assert.Contains(t, out, "content-range: bytes */10\r\n")
+
+	// Test: Malformed and overlapping ranges are ignored
+	assert.True(t, strings.HasPrefix(serveContent(t, "Range: bytes=5-1\r\n"), "HTTP/1.1 200 OK\r\n"))
+	assert.True(t, strings.HasPrefix(serveContent(t, "Range: bytes=0-9,0-9\r\n"), "HTTP/1.1 200 OK\r\n"))
+
+	// Test: If-Range with the current validators
+	out = serveContent(t, "Range: bytes=0-0\r\n")
+	_, rest, _ = strings.Cut(out, "etag: ")
+	etag, _, _ := strings.Cut(rest, "\r\n")
+	assert.True(t, strings.HasPrefix(serveContent(t, "Range: bytes=0-0\r\n", "If-Range: "+etag+"\r\n"), "HTTP/1.1 206 Partial Content\r\n"))
+	assert.True(t, strings.HasPrefix(serveContent(t, "Range: bytes=0-0\r\n", "If-Range: "+headers.FormatTime(testModTime)+"\r\n"), "HTTP/1.1 206 Partial Content\r\n"))
+
+	// Test: If-Range that no longer matches sends everything
+	assert.True(t, strings.HasPrefix(serveContent(t, "Range: bytes=0-0\r\n", "If-Range: \"old\"\r\n"), "HTTP/1.1 200 OK\r\n"))
+	assert.True(t, strings.HasPrefix(serveContent(t, "Range: bytes=0-0\r\n", "If-Range: W/"+etag+"\r\n"), "HTTP/1.1 200 OK\r\n"))
+	assert.True(t, strings.HasPrefix(serveContent(t, "Range: bytes=0-0\r\n", "If-Range: "+headers.FormatTime(testModTime.Add(-time.Hour))+"\r\n"), "HTTP/1.1 200 OK\r\n"))
+}
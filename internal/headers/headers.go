@@ -2,6 +2,7 @@ package headers
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"slices"
 	"strings"
@@ -9,6 +10,16 @@ import (
 
 const crlf = "\r\n"
 
+var (
+	// ErrWhitespaceBeforeColon is returned for field lines like "Host : x",
+	// which RFC 9112 section 5.1 requires servers to reject.
+	ErrWhitespaceBeforeColon = errors.New("whitespace between header name and colon")
+	ErrMissingColon          = errors.New("header line without colon")
+	ErrInvalidFieldName      = errors.New("invalid header name")
+	ErrInvalidFieldValue     = errors.New("invalid header value")
+	ErrObsFold               = errors.New("obsolete line folding in header")
+)
+
 type Headers map[string]string
 
 func NewHeaders() Headers {
@@ -25,20 +36,57 @@ func (h Headers) Parse(data []byte) (n int, done bool, err error) {
 		return 2, true, nil
 	}
 
-	parts := bytes.SplitN(data[:idx], []byte(":"), 2)
-	key := strings.ToLower(string(parts[0]))
+	key, value, err := parseFieldLine(data[:idx])
+	if err != nil {
+		return 0, false, err
+	}
+	h.Set(key, value)
+	return idx + 2, false, nil
+}
+
+func parseFieldLine(line []byte) (key, value string, err error) {
+	name, rawValue, found := bytes.Cut(line, []byte(":"))
+	if !found {
+		return "", "", fmt.Errorf("%w: %q", ErrMissingColon, line)
+	}
+	key = strings.ToLower(string(name))
 
-	if key != strings.TrimRight(key, " ") {
-		return 0, false, fmt.Errorf("invalid header name: %s", key)
+	if key != strings.TrimRight(key, " \t") {
+		return "", "", fmt.Errorf("%w: %s", ErrWhitespaceBeforeColon, key)
 	}
 
-	value := bytes.TrimSpace(parts[1])
 	key = strings.TrimSpace(key)
-	if !validTokens([]byte(key)) {
-		return 0, false, fmt.Errorf("invalid header token found: %s", key)
+	if key == "" || !validTokens([]byte(key)) {
+		return "", "", fmt.Errorf("%w: %q", ErrInvalidFieldName, key)
 	}
-	h.Set(key, string(value))
-	return idx + 2, false, nil
+	value = trimOWS(string(rawValue))
+	if !ValidFieldValue(value) {
+		return "", "", fmt.Errorf("%w for %s: %q", ErrInvalidFieldValue, key, value)
+	}
+	return key, value, nil
+}
+
+// ValidFieldValue reports whether v only contains the characters RFC 9110
+// section 5.5 allows in a field value: visible ASCII, SP, HTAB and obs-text.
+// NUL, CR, LF and the other control characters are rejected.
+func ValidFieldValue(v string) bool {
+	for i := 0; i < len(v); i++ {
+		c := v[i]
+		if (c < ' ' && c != '\t') || c == 0x7f {
+			return false
+		}
+	}
+	return true
+}
+
+// ValidFieldName reports whether name is a non-empty token.
+func ValidFieldName(name string) bool {
+	return name != "" && validTokens([]byte(name))
+}
+
+// trimOWS strips the optional whitespace (SP and HTAB) around a field value.
+func trimOWS(s string) string {
+	return strings.Trim(s, " \t")
 }
 
 func (h Headers) Get(key string) (string, bool) {
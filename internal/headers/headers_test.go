@@ -89,3 +89,74 @@ func TestParseHeaders(t *testing.T) {
 	assert.Equal(t, 23, n)
 	assert.False(t, done)
 }
+
+func TestParseFieldValues(t *testing.T) {
+	// Test: Line without a colon
+	headers := NewHeaders()
+	data := []byte("Host localhost\r\n\r\n")
+	n, done, err := headers.Parse(data)
+	require.ErrorIs(t, err, ErrMissingColon)
+	assert.Equal(t, 0, n)
+	assert.False(t, done)
+
+	// Test: Empty header name
+	headers = NewHeaders()
+	data = []byte(": value\r\n\r\n")
+	_, _, err = headers.Parse(data)
+	require.ErrorIs(t, err, ErrInvalidFieldName)
+
+	// Test: NUL in value
+	headers = NewHeaders()
+	data = []byte("X-Name: a\x00b\r\n\r\n")
+	_, _, err = headers.Parse(data)
+	require.ErrorIs(t, err, ErrInvalidFieldValue)
+
+	// Test: Bare CR in value
+	headers = NewHeaders()
+	data = []byte("X-Name: a\rb\r\n\r\n")
+	_, _, err = headers.Parse(data)
+	require.ErrorIs(t, err, ErrInvalidFieldValue)
+
+	// Test: Other control characters in value
+	headers = NewHeaders()
+	data = []byte("X-Name: a\x1bb\r\n\r\n")
+	_, _, err = headers.Parse(data)
+	require.ErrorIs(t, err, ErrInvalidFieldValue)
+
+	// Test: HTAB and obs-text are allowed
+	headers = NewHeaders()
+	data = []byte("X-Name: a\tb \xe2\x9c\x93\t\r\n\r\n")
+	n, _, err = headers.Parse(data)
+	require.NoError(t, err)
+	assert.Equal(t, "a\tb \xe2\x9c\x93", headers["x-name"])
+	assert.Equal(t, 18, n)
+}
+
+func TestParserObsFold(t *testing.T) {
+	// Test: Strict mode rejects folded lines
+	p := NewParser(NewHeaders(), FoldReject)
+	data := []byte("X-Long: first\r\n  second\r\n\r\n")
+	n, _, err := p.Parse(data)
+	require.NoError(t, err)
+	_, _, err = p.Parse(data[n:])
+	require.ErrorIs(t, err, ErrObsFold)
+
+	// Test: Lenient mode unfolds into a single space
+	p = NewParser(NewHeaders(), FoldUnfold)
+	total := 0
+	for {
+		n, done, err := p.Parse(data[total:])
+		require.NoError(t, err)
+		total += n
+		if done {
+			break
+		}
+	}
+	assert.Equal(t, "first second", p.Headers["x-long"])
+	assert.Equal(t, len(data), total)
+
+	// Test: A fold without a previous field is rejected in both modes
+	p = NewParser(NewHeaders(), FoldUnfold)
+	_, _, err = p.Parse([]byte(" Host: localhost\r\n\r\n"))
+	require.ErrorIs(t, err, ErrObsFold)
+}
@@ -0,0 +1,68 @@
+package headers
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// FoldMode decides how a Parser treats obsolete line folding, i.e. a field
+// line that starts with whitespace and continues the previous field's value.
+type FoldMode int
+
+const (
+	// FoldReject fails on obs-fold, which RFC 9112 section 5.2 recommends
+	// for servers.
+	FoldReject FoldMode = iota
+	// FoldUnfold replaces each obs-fold with a single space.
+	FoldUnfold
+)
+
+// Parser parses a header section line by line like Headers.Parse, but
+// remembers the previous field so continuation lines can be handled. Unlike
+// Headers.Parse it never trims whitespace in front of a field name.
+type Parser struct {
+	Headers Headers
+	Fold    FoldMode
+	lastKey string
+}
+
+func NewParser(h Headers, fold FoldMode) *Parser {
+	return &Parser{
+		Headers: h,
+		Fold:    fold,
+	}
+}
+
+func (p *Parser) Parse(data []byte) (n int, done bool, err error) {
+	idx := bytes.Index(data, []byte(crlf))
+	if idx == -1 {
+		return 0, false, nil
+	}
+	if idx == 0 {
+		// headers are done, consume the CRLF
+		return 2, true, nil
+	}
+
+	line := data[:idx]
+	if line[0] == ' ' || line[0] == '\t' {
+		if p.Fold == FoldReject || p.lastKey == "" {
+			return 0, false, fmt.Errorf("%w: %q", ErrObsFold, line)
+		}
+		value := trimOWS(string(line))
+		if !ValidFieldValue(value) {
+			return 0, false, fmt.Errorf("%w for %s: %q", ErrInvalidFieldValue, p.lastKey, value)
+		}
+		if value != "" {
+			p.Headers[p.lastKey] += " " + value
+		}
+		return idx + 2, false, nil
+	}
+
+	key, value, err := parseFieldLine(line)
+	if err != nil {
+		return 0, false, err
+	}
+	p.Headers.Set(key, value)
+	p.lastKey = key
+	return idx + 2, false, nil
+}
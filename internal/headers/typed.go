@@ -0,0 +1,237 @@
+package headers
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TimeFormat is the IMF-fixdate format from RFC 9110 section 5.6.7, the only
+// format senders may use for dates.
+const TimeFormat = "Mon, 02 Jan 2006 15:04:05 GMT"
+
+// obsolete date formats that recipients still have to accept
+const (
+	rfc850Format  = "Monday, 02-Jan-06 15:04:05 GMT"
+	asctimeFormat = "Mon Jan _2 15:04:05 2006"
+)
+
+var (
+	ErrInvalidContentLength     = errors.New("invalid Content-Length")
+	ErrConflictingContentLength = errors.New("conflicting Content-Length values")
+	ErrInvalidDate              = errors.New("invalid date")
+)
+
+// ContentLength returns the parsed Content-Length. Repeated headers are
+// accepted only if every value is identical. ok is false if the header is
+// absent.
+func (h Headers) ContentLength() (n int64, ok bool, err error) {
+	v, ok := h.Get("Content-Length")
+	if !ok {
+		return 0, false, nil
+	}
+	var length int64 = -1
+	for _, part := range strings.Split(v, ",") {
+		part = trimOWS(part)
+		n, err := ParseDecimal(part)
+		if err != nil {
+			return 0, true, fmt.Errorf("%w: %q", ErrInvalidContentLength, part)
+		}
+		if length != -1 && n != length {
+			return 0, true, fmt.Errorf("%w: %s", ErrConflictingContentLength, v)
+		}
+		length = n
+	}
+	return length, true, nil
+}
+
+// ParseDecimal parses a non-negative decimal number made of digits only, as
+// used by Content-Length and byte ranges; strconv alone would also accept
+// signs.
+func ParseDecimal(s string) (int64, error) {
+	if s == "" {
+		return 0, fmt.Errorf("empty number")
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return 0, fmt.Errorf("invalid digit %q", s[i])
+		}
+	}
+	return strconv.ParseInt(s, 10, 64)
+}
+
+// ContentType returns the lowercased media type and its parameters. Both are
+// empty if the header is absent.
+func (h Headers) ContentType() (mediaType string, params map[string]string, err error) {
+	v, ok := h.Get("Content-Type")
+	if !ok {
+		return "", nil, nil
+	}
+	return mime.ParseMediaType(v)
+}
+
+// Time parses a date header such as Date or If-Modified-Since. ok is false
+// if the header is absent.
+func (h Headers) Time(key string) (t time.Time, ok bool, err error) {
+	v, ok := h.Get(key)
+	if !ok {
+		return time.Time{}, false, nil
+	}
+	t, err = ParseTime(v)
+	return t, true, err
+}
+
+func (h Headers) Date() (time.Time, bool, error) {
+	return h.Time("Date")
+}
+
+func (h Headers) IfModifiedSince() (time.Time, bool, error) {
+	return h.Time("If-Modified-Since")
+}
+
+func (h Headers) IfUnmodifiedSince() (time.Time, bool, error) {
+	return h.Time("If-Unmodified-Since")
+}
+
+func (h Headers) LastModified() (time.Time, bool, error) {
+	return h.Time("Last-Modified")
+}
+
+// ParseTime parses an HTTP date in IMF-fixdate or one of the two obsolete
+// formats from RFC 9110 section 5.6.7.
+func ParseTime(s string) (time.Time, error) {
+	for _, layout := range []string{TimeFormat, rfc850Format, asctimeFormat} {
+		t, err := time.Parse(layout, s)
+		if err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%w: %q", ErrInvalidDate, s)
+}
+
+// FormatTime formats t as IMF-fixdate.
+func FormatTime(t time.Time) string {
+	return t.UTC().Format(TimeFormat)
+}
+
+// List splits a comma-separated header like Connection or Accept-Encoding into
+// its elements, trimming whitespace, skipping empty elements and keeping
+// commas inside quoted strings.
+func (h Headers) List(key string) []string {
+	v, ok := h.Get(key)
+	if !ok {
+		return nil
+	}
+	return splitList(v)
+}
+
+// HasToken reports whether the list header key contains token, compared
+// case-insensitively.
+func (h Headers) HasToken(key, token string) bool {
+	for _, element := range h.List(key) {
+		if strings.EqualFold(element, token) {
+			return true
+		}
+	}
+	return false
+}
+
+func splitList(v string) []string {
+	var elements []string
+	inQuotes := false
+	start := 0
+	for i := 0; i < len(v); i++ {
+		switch v[i] {
+		case '"':
+			inQuotes = !inQuotes
+		case '\\':
+			if inQuotes {
+				i++
+			}
+		case ',':
+			if !inQuotes {
+				elements = appendElement(elements, v[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return appendElement(elements, v[start:])
+}
+
+func appendElement(elements []string, element string) []string {
+	element = trimOWS(element)
+	if element == "" {
+		return elements
+	}
+	return append(elements, element)
+}
+
+// QualityValue is one element of an Accept-style header.
+type QualityValue struct {
+	Value  string
+	Q      float64
+	Params map[string]string
+}
+
+// QualityValues parses an Accept-style header into its elements, ordered by
+// descending q-value. Elements without a q parameter have q=1; elements with
+// a malformed q-value are dropped.
+func (h Headers) QualityValues(key string) []QualityValue {
+	var values []QualityValue
+	for _, element := range h.List(key) {
+		qv, ok := parseQualityValue(element)
+		if ok {
+			values = append(values, qv)
+		}
+	}
+	sort.SliceStable(values, func(i, j int) bool {
+		return values[i].Q > values[j].Q
+	})
+	return values
+}
+
+func parseQualityValue(element string) (QualityValue, bool) {
+	parts := strings.Split(element, ";")
+	qv := QualityValue{
+		Value: strings.ToLower(trimOWS(parts[0])),
+		Q:     1,
+	}
+	for _, param := range parts[1:] {
+		name, value, _ := strings.Cut(param, "=")
+		name = strings.ToLower(trimOWS(name))
+		value = strings.Trim(trimOWS(value), `"`)
+		if name == "q" {
+			q, ok := parseQ(value)
+			if !ok {
+				return QualityValue{}, false
+			}
+			qv.Q = q
+			// anything after q is an accept-ext, not a media type parameter
+			break
+		}
+		if qv.Params == nil {
+			qv.Params = map[string]string{}
+		}
+		qv.Params[name] = value
+	}
+	return qv, qv.Value != ""
+}
+
+// parseQ parses a qvalue: 0 or 1 with at most three decimals.
+func parseQ(s string) (float64, bool) {
+	if s == "" || len(s) > 5 || (s[0] != '0' && s[0] != '1') {
+		return 0, false
+	}
+	if len(s) > 1 && s[1] != '.' {
+		return 0, false
+	}
+	q, err := strconv.ParseFloat(s, 64)
+	if err != nil || q < 0 || q > 1 {
+		return 0, false
+	}
+	return q, true
+}
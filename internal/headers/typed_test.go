@@ -0,0 +1,123 @@
+package headers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContentLength(t *testing.T) {
+	// Test: Absent
+	h := NewHeaders()
+	_, ok, err := h.ContentLength()
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	// Test: Valid value
+	h.Set("Content-Length", "42")
+	n, ok, err := h.ContentLength()
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, int64(42), n)
+
+	// Test: Identical duplicates
+	h.Set("Content-Length", "42")
+	n, _, err = h.ContentLength()
+	require.NoError(t, err)
+	assert.Equal(t, int64(42), n)
+
+	// Test: Conflicting duplicates
+	h.Set("Content-Length", "7")
+	_, _, err = h.ContentLength()
+	require.ErrorIs(t, err, ErrConflictingContentLength)
+
+	// Test: Negative and signed values
+	h.Override("Content-Length", "-1")
+	_, _, err = h.ContentLength()
+	require.ErrorIs(t, err, ErrInvalidContentLength)
+	h.Override("Content-Length", "+1")
+	_, _, err = h.ContentLength()
+	require.ErrorIs(t, err, ErrInvalidContentLength)
+}
+
+func TestParseDecimal(t *testing.T) {
+	// Test: Digits only
+	n, err := ParseDecimal("0042")
+	require.NoError(t, err)
+	assert.Equal(t, int64(42), n)
+
+	// Test: Empty, signed and overflowing values
+	for _, s := range []string{"", "+1", "-1", " 1", "99999999999999999999"} {
+		_, err = ParseDecimal(s)
+		require.Error(t, err, s)
+	}
+}
+
+func TestContentType(t *testing.T) {
+	// Test: Media type with parameters
+	h := NewHeaders()
+	h.Set("Content-Type", `Text/HTML; Charset="utf-8"`)
+	mediaType, params, err := h.ContentType()
+	require.NoError(t, err)
+	assert.Equal(t, "text/html", mediaType)
+	assert.Equal(t, "utf-8", params["charset"])
+
+	// Test: Absent
+	mediaType, _, err = NewHeaders().ContentType()
+	require.NoError(t, err)
+	assert.Equal(t, "", mediaType)
+}
+
+func TestTimeHeaders(t *testing.T) {
+	want := time.Date(1994, time.November, 6, 8, 49, 37, 0, time.UTC)
+
+	// Test: IMF-fixdate and the obsolete formats
+	for _, v := range []string{"Sun, 06 Nov 1994 08:49:37 GMT", "Sunday, 06-Nov-94 08:49:37 GMT", "Sun Nov  6 08:49:37 1994"} {
+		h := NewHeaders()
+		h.Set("If-Modified-Since", v)
+		got, ok, err := h.IfModifiedSince()
+		require.NoError(t, err, v)
+		assert.True(t, ok)
+		assert.True(t, want.Equal(got), v)
+	}
+
+	// Test: Invalid date
+	h := NewHeaders()
+	h.Set("Date", "yesterday")
+	_, ok, err := h.Date()
+	assert.True(t, ok)
+	require.ErrorIs(t, err, ErrInvalidDate)
+
+	// Test: Formatting
+	assert.Equal(t, "Sun, 06 Nov 1994 08:49:37 GMT", FormatTime(want.In(time.FixedZone("CET", 3600))))
+}
+
+func TestList(t *testing.T) {
+	// Test: Whitespace, empty elements and quoted commas
+	h := NewHeaders()
+	h.Set("Connection", " keep-alive , ,Upgrade")
+	h.Set("X-Quoted", `a, "b, c", d`)
+	assert.Equal(t, []string{"keep-alive", "Upgrade"}, h.List("Connection"))
+	assert.Equal(t, []string{"a", `"b, c"`, "d"}, h.List("X-Quoted"))
+	assert.True(t, h.HasToken("Connection", "upgrade"))
+	assert.False(t, h.HasToken("Connection", "close"))
+	assert.Nil(t, h.List("Accept-Encoding"))
+}
+
+func TestQualityValues(t *testing.T) {
+	// Test: Sorted by q, params kept, malformed q dropped
+	h := NewHeaders()
+	h.Set("Accept", "text/html;level=1;q=0.5, application/json, text/*;q=0.8, image/png;q=2, */*;q=0")
+	values := h.QualityValues("Accept")
+	require.Len(t, values, 4)
+	assert.Equal(t, "application/json", values[0].Value)
+	assert.Equal(t, 1.0, values[0].Q)
+	assert.Equal(t, "text/*", values[1].Value)
+	assert.Equal(t, "text/html", values[2].Value)
+	assert.Equal(t, 0.5, values[2].Q)
+	assert.Equal(t, "1", values[2].Params["level"])
+	assert.Equal(t, "*/*", values[3].Value)
+	assert.Equal(t, 0.0, values[3].Q)
+}
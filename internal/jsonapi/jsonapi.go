@@ -0,0 +1,83 @@
+package jsonapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/UUest/httpfromtcp/internal/request"
+	"github.com/UUest/httpfromtcp/internal/response"
+)
+
+// DefaultMaxBodySize caps the body DecodeJSON will accept.
+const DefaultMaxBodySize = 1 << 20
+
+var (
+	ErrUnsupportedMediaType = errors.New("body is not JSON")
+	ErrBodyTooLarge         = errors.New("JSON body too large")
+	ErrInvalidJSON          = errors.New("invalid JSON body")
+)
+
+// DecodeJSON decodes the request body into v. The body must be
+// application/json or another +json type, hold exactly one JSON value, fit
+// in DefaultMaxBodySize and only use fields that v knows about.
+func DecodeJSON(req *request.Request, v any) error {
+	return DecodeJSONLimit(req, v, DefaultMaxBodySize)
+}
+
+// DecodeJSONLimit is DecodeJSON with a custom body size limit.
+func DecodeJSONLimit(req *request.Request, v any, maxSize int64) error {
+	mediaType, _, err := req.Headers.ContentType()
+	if err != nil || (mediaType != "application/json" && !strings.HasSuffix(mediaType, "+json")) {
+		return fmt.Errorf("%w: Content-Type %q", ErrUnsupportedMediaType, mediaType)
+	}
+	if n, ok, _ := req.Headers.ContentLength(); ok && n > maxSize {
+		return fmt.Errorf("%w: %d bytes, limit %d", ErrBodyTooLarge, n, maxSize)
+	}
+	body, err := req.ReadBodyLimit(maxSize)
+	if errors.Is(err, request.ErrBodyTooLarge) {
+		return fmt.Errorf("%w: limit %d", ErrBodyTooLarge, maxSize)
+	}
+	if err != nil {
+		return err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.DisallowUnknownFields()
+	err = dec.Decode(v)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidJSON, err)
+	}
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return fmt.Errorf("%w: trailing data after JSON value", ErrInvalidJSON)
+	}
+	return nil
+}
+
+// WriteJSON writes a complete response with v encoded as the body.
+func WriteJSON(w *response.Writer, statusCode response.StatusCode, v any) error {
+	return write(w, statusCode, "application/json", v)
+}
+
+func write(w *response.Writer, statusCode response.StatusCode, contentType string, v any) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	body = append(body, '\n')
+	err = w.WriteStatusLine(statusCode)
+	if err != nil {
+		return err
+	}
+	h := response.GetDefaultHeaders(len(body))
+	h.Override("Content-Type", contentType)
+	err = w.WriteHeaders(h)
+	if err != nil {
+		return err
+	}
+	_, err = w.WriteBody(body)
+	return err
+}
@@ -0,0 +1,100 @@
+package jsonapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/UUest/httpfromtcp/internal/request"
+	"github.com/UUest/httpfromtcp/internal/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type item struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+func newRequest(t *testing.T, contentType, body string) *request.Request {
+	t.Helper()
+	raw := "POST /items HTTP/1.1\r\nHost: localhost:42069\r\n"
+	if contentType != "" {
+		raw += "Content-Type: " + contentType + "\r\n"
+	}
+	raw += fmt.Sprintf("Content-Length: %d\r\n\r\n%s", len(body), body)
+	req, err := request.HeadersFromReader(strings.NewReader(raw))
+	require.NoError(t, err)
+	return req
+}
+
+func TestDecodeJSON(t *testing.T) {
+	// Test: Valid body
+	var v item
+	require.NoError(t, DecodeJSON(newRequest(t, "application/json; charset=utf-8", `{"name":"a","count":2}`), &v))
+	assert.Equal(t, item{Name: "a", Count: 2}, v)
+
+	// Test: +json media types are accepted
+	require.NoError(t, DecodeJSON(newRequest(t, "application/merge-patch+json", `{"name":"b"}`), &v))
+
+	// Test: Wrong or missing Content-Type
+	require.ErrorIs(t, DecodeJSON(newRequest(t, "text/plain", `{}`), &v), ErrUnsupportedMediaType)
+	require.ErrorIs(t, DecodeJSON(newRequest(t, "", `{}`), &v), ErrUnsupportedMediaType)
+
+	// Test: Unknown fields, syntax errors and trailing data
+	require.ErrorIs(t, DecodeJSON(newRequest(t, "application/json", `{"name":"a","extra":1}`), &v), ErrInvalidJSON)
+	require.ErrorIs(t, DecodeJSON(newRequest(t, "application/json", `{"name":`), &v), ErrInvalidJSON)
+	require.ErrorIs(t, DecodeJSON(newRequest(t, "application/json", `{} {}`), &v), ErrInvalidJSON)
+
+	// Test: Size limit
+	require.ErrorIs(t, DecodeJSONLimit(newRequest(t, "application/json", `{"name":"abcdef"}`), &v, 8), ErrBodyTooLarge)
+
+	// Test: Chunked body fails as soon as it goes over the limit
+	req, err := request.HeadersFromReader(io.MultiReader(
+		strings.NewReader("POST /items HTTP/1.1\r\nHost: localhost:42069\r\nContent-Type: application/json\r\nTransfer-Encoding: chunked\r\n\r\n11\r\n{\"name\":\"abcdef\"}\r\n"),
+		iotest.ErrReader(errors.New("read past the limit")),
+	))
+	require.NoError(t, err)
+	require.ErrorIs(t, DecodeJSONLimit(req, &v, 8), ErrBodyTooLarge)
+}
+
+func TestWriteJSON(t *testing.T) {
+	// Test: Body and headers
+	var out strings.Builder
+	require.NoError(t, WriteJSON(response.NewWriter(&out), response.StatusCodeSuccess, item{Name: "a", Count: 1}))
+	assert.True(t, strings.HasPrefix(out.String(), "HTTP/1.1 200 OK\r\n"))
+	assert.Contains(t, out.String(), "content-type: application/json\r\n")
+	assert.Contains(t, out.String(), "content-length: 23\r\n")
+	assert.True(t, strings.HasSuffix(out.String(), "\r\n\r\n{\"name\":\"a\",\"count\":1}\n"))
+}
+
+func TestWriteProblem(t *testing.T) {
+	// Test: Standard members and extensions are merged
+	var out strings.Builder
+	p := NewProblem(response.StatusCodeBadRequest, "missing name")
+	p.Extensions = map[string]any{"field": "name"}
+	require.NoError(t, WriteProblem(response.NewWriter(&out), p))
+	assert.True(t, strings.HasPrefix(out.String(), "HTTP/1.1 400 Bad Request\r\n"))
+	assert.Contains(t, out.String(), "content-type: application/problem+json\r\n")
+	_, body, _ := strings.Cut(out.String(), "\r\n\r\n")
+	var got map[string]any
+	require.NoError(t, json.Unmarshal([]byte(body), &got))
+	assert.Equal(t, map[string]any{
+		"title":  "Bad Request",
+		"status": float64(400),
+		"detail": "missing name",
+		"field":  "name",
+	}, got)
+
+	// Test: Decode errors map to status codes
+	out.Reset()
+	require.NoError(t, WriteDecodeError(response.NewWriter(&out), fmt.Errorf("%w: x", ErrBodyTooLarge)))
+	assert.True(t, strings.HasPrefix(out.String(), "HTTP/1.1 413 Content Too Large\r\n"))
+	out.Reset()
+	require.NoError(t, WriteDecodeError(response.NewWriter(&out), fmt.Errorf("%w: x", ErrUnsupportedMediaType)))
+	assert.True(t, strings.HasPrefix(out.String(), "HTTP/1.1 415 Unsupported Media Type\r\n"))
+}
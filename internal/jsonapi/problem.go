@@ -0,0 +1,70 @@
+package jsonapi
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/UUest/httpfromtcp/internal/response"
+)
+
+// Problem is a problem details object from RFC 9457. Extensions are added as
+// extra members next to the standard ones.
+type Problem struct {
+	Type       string
+	Title      string
+	Status     response.StatusCode
+	Detail     string
+	Instance   string
+	Extensions map[string]any
+}
+
+// NewProblem returns a Problem for statusCode with the reason phrase as its
+// title. Type is left empty, which means "about:blank".
+func NewProblem(statusCode response.StatusCode, detail string) *Problem {
+	return &Problem{
+		Title:  response.StatusText(statusCode),
+		Status: statusCode,
+		Detail: detail,
+	}
+}
+
+func (p *Problem) MarshalJSON() ([]byte, error) {
+	m := make(map[string]any, len(p.Extensions)+5)
+	for k, v := range p.Extensions {
+		m[k] = v
+	}
+	if p.Type != "" {
+		m["type"] = p.Type
+	}
+	if p.Title != "" {
+		m["title"] = p.Title
+	}
+	if p.Status != 0 {
+		m["status"] = int(p.Status)
+	}
+	if p.Detail != "" {
+		m["detail"] = p.Detail
+	}
+	if p.Instance != "" {
+		m["instance"] = p.Instance
+	}
+	return json.Marshal(m)
+}
+
+// WriteProblem writes p as an application/problem+json response with p.Status
+// as the status code.
+func WriteProblem(w *response.Writer, p *Problem) error {
+	return write(w, p.Status, "application/problem+json", p)
+}
+
+// WriteDecodeError answers a DecodeJSON error with 415, 413 or 400.
+func WriteDecodeError(w *response.Writer, err error) error {
+	statusCode := response.StatusCodeBadRequest
+	switch {
+	case errors.Is(err, ErrUnsupportedMediaType):
+		statusCode = response.StatusCodeUnsupportedMediaType
+	case errors.Is(err, ErrBodyTooLarge):
+		statusCode = response.StatusCodeContentTooLarge
+	}
+	return WriteProblem(w, NewProblem(statusCode, err.Error()))
+}
@@ -0,0 +1,143 @@
+package multipart
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+
+	"github.com/UUest/httpfromtcp/internal/headers"
+)
+
+// Form is a fully read multipart/form-data body.
+type Form struct {
+	Value map[string][]string
+	File  map[string][]*FileHeader
+}
+
+// FileHeader describes a file part. Its content is either held in memory or
+// in a temp file, depending on how much memory was left when it was read.
+type FileHeader struct {
+	Filename string
+	Headers  headers.Headers
+	Size     int64
+
+	content []byte
+	tmpfile string
+}
+
+// File is the content of a FileHeader.
+type File interface {
+	io.Reader
+	io.ReaderAt
+	io.Seeker
+	io.Closer
+}
+
+// ReadForm reads every part. Non-file values and file contents are kept in
+// memory up to maxMemory bytes in total; file parts that do not fit are
+// written to temp files, which RemoveAll deletes.
+func (r *Reader) ReadForm(maxMemory int64) (*Form, error) {
+	form := &Form{
+		Value: map[string][]string{},
+		File:  map[string][]*FileHeader{},
+	}
+	err := r.readForm(form, maxMemory)
+	if err != nil {
+		form.RemoveAll()
+		return nil, err
+	}
+	return form, nil
+}
+
+func (r *Reader) readForm(form *Form, maxMemory int64) error {
+	remaining := maxMemory
+	for {
+		p, err := r.NextPart()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		name := p.FormName()
+		if name == "" {
+			continue
+		}
+		filename := p.FileName()
+
+		var b bytes.Buffer
+		if filename == "" {
+			_, err = io.Copy(&b, io.LimitReader(p, remaining+1))
+			if err != nil {
+				return err
+			}
+			if int64(b.Len()) > remaining {
+				return ErrMessageTooLarge
+			}
+			remaining -= int64(b.Len())
+			form.Value[name] = append(form.Value[name], b.String())
+			continue
+		}
+
+		fh := &FileHeader{Filename: filename, Headers: p.Headers}
+		// register before filling so RemoveAll finds a half-written temp file
+		form.File[name] = append(form.File[name], fh)
+		n, err := io.Copy(&b, io.LimitReader(p, remaining+1))
+		if err != nil {
+			return err
+		}
+		if n <= remaining {
+			fh.content = b.Bytes()
+			fh.Size = n
+			remaining -= n
+			continue
+		}
+		f, err := os.CreateTemp("", "multipart-")
+		if err != nil {
+			return err
+		}
+		fh.tmpfile = f.Name()
+		size, err := io.Copy(f, io.MultiReader(&b, p))
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return err
+		}
+		fh.Size = size
+	}
+}
+
+// Open returns the file content.
+func (fh *FileHeader) Open() (File, error) {
+	if fh.tmpfile != "" {
+		return os.Open(fh.tmpfile)
+	}
+	return nopCloser{bytes.NewReader(fh.content)}, nil
+}
+
+type nopCloser struct {
+	*bytes.Reader
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
+
+// RemoveAll deletes the temp files of the form.
+func (f *Form) RemoveAll() error {
+	var err error
+	for _, fhs := range f.File {
+		for _, fh := range fhs {
+			if fh.tmpfile == "" {
+				continue
+			}
+			rerr := os.Remove(fh.tmpfile)
+			if rerr != nil && !errors.Is(rerr, os.ErrNotExist) && err == nil {
+				err = rerr
+			}
+		}
+	}
+	return err
+}
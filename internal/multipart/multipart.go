@@ -0,0 +1,254 @@
+package multipart
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"path/filepath"
+
+	"github.com/UUest/httpfromtcp/internal/headers"
+)
+
+// peekSize is how much of a part body is looked at at once when searching for
+// the next delimiter.
+const peekSize = 4096
+
+// maxHeaderBytes caps the header section of a single part.
+const maxHeaderBytes = 10 << 10
+
+var (
+	ErrMalformed       = errors.New("multipart: malformed body")
+	ErrPartTooLarge    = errors.New("multipart: part too large")
+	ErrMessageTooLarge = errors.New("multipart: message too large")
+)
+
+// Reader iterates over the parts of a multipart body as it streams in. Only
+// the current part can be read; calling NextPart skips whatever is left of it.
+type Reader struct {
+	// MaxPartSize limits the body of each part and MaxTotalSize the whole
+	// message, headers included. Zero means no limit.
+	MaxPartSize  int64
+	MaxTotalSize int64
+
+	br             *bufio.Reader
+	dashBoundary   []byte
+	nlDashBoundary []byte
+	current        *Part
+	started        bool
+	done           bool
+	total          int64
+}
+
+// NewReader returns a Reader for a body delimited by boundary, the parameter
+// of the same name in the Content-Type header.
+func NewReader(r io.Reader, boundary string) *Reader {
+	dashBoundary := []byte("--" + boundary)
+	return &Reader{
+		br:             bufio.NewReaderSize(r, peekSize+len(dashBoundary)+2),
+		dashBoundary:   dashBoundary,
+		nlDashBoundary: append([]byte("\r\n"), dashBoundary...),
+	}
+}
+
+// Part is one section of a multipart body. Read returns its body.
+type Part struct {
+	Headers headers.Headers
+
+	mr  *Reader
+	n   int64
+	eof bool
+}
+
+// NextPart returns the next part, or io.EOF after the closing delimiter.
+func (r *Reader) NextPart() (*Part, error) {
+	if r.done {
+		return nil, io.EOF
+	}
+	if r.current != nil {
+		_, err := io.Copy(io.Discard, r.current)
+		if err != nil {
+			return nil, err
+		}
+		r.current = nil
+		// the part stopped right before the CRLF of the delimiter
+		_, err = r.br.Discard(2)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrMalformed, err)
+		}
+	}
+
+	for {
+		line, err := r.readLine()
+		if errors.Is(err, io.ErrUnexpectedEOF) && r.isCloseDelimiter(line) {
+			// the close delimiter may end the body without a CRLF
+			r.done = true
+			return nil, io.EOF
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.HasPrefix(line, r.dashBoundary) {
+			if r.started {
+				return nil, fmt.Errorf("%w: expected delimiter, got %q", ErrMalformed, line)
+			}
+			// preamble
+			continue
+		}
+		if r.isCloseDelimiter(line) {
+			r.done = true
+			return nil, io.EOF
+		}
+		if len(bytes.TrimRight(line[len(r.dashBoundary):], " \t\r\n")) != 0 {
+			if r.started {
+				return nil, fmt.Errorf("%w: bad delimiter %q", ErrMalformed, line)
+			}
+			continue
+		}
+		break
+	}
+	r.started = true
+
+	p := &Part{Headers: headers.NewHeaders(), mr: r}
+	parser := headers.NewParser(p.Headers, headers.FoldReject)
+	headerBytes := 0
+	for {
+		line, err := r.readLine()
+		if err != nil {
+			return nil, err
+		}
+		headerBytes += len(line)
+		if headerBytes > maxHeaderBytes {
+			return nil, fmt.Errorf("%w: part headers exceed %d bytes", ErrMalformed, maxHeaderBytes)
+		}
+		_, done, err := parser.Parse(line)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrMalformed, err)
+		}
+		if done {
+			break
+		}
+	}
+	r.current = p
+	return p, nil
+}
+
+// isCloseDelimiter reports whether line is the delimiter that ends the body.
+func (r *Reader) isCloseDelimiter(line []byte) bool {
+	rest, ok := bytes.CutPrefix(line, r.dashBoundary)
+	return ok && bytes.Equal(bytes.TrimRight(rest, " \t\r\n"), []byte("--"))
+}
+
+// readLine reads one CRLF-terminated line and counts it against MaxTotalSize.
+// On EOF it returns the unterminated rest along with io.ErrUnexpectedEOF.
+func (r *Reader) readLine() ([]byte, error) {
+	line, err := r.br.ReadSlice('\n')
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return line, fmt.Errorf("%w: %w", ErrMalformed, io.ErrUnexpectedEOF)
+		}
+		if errors.Is(err, bufio.ErrBufferFull) {
+			return nil, fmt.Errorf("%w: line too long", ErrMalformed)
+		}
+		return nil, err
+	}
+	if len(line) < 2 || line[len(line)-2] != '\r' {
+		return nil, fmt.Errorf("%w: bare LF", ErrMalformed)
+	}
+	return line, r.count(len(line))
+}
+
+func (r *Reader) count(n int) error {
+	r.total += int64(n)
+	if r.MaxTotalSize > 0 && r.total > r.MaxTotalSize {
+		return fmt.Errorf("%w: limit %d bytes", ErrMessageTooLarge, r.MaxTotalSize)
+	}
+	return nil
+}
+
+// Read reads the part body up to the next delimiter.
+func (p *Part) Read(b []byte) (int, error) {
+	if p.eof {
+		return 0, io.EOF
+	}
+	r := p.mr
+	// use what is buffered so parts stream, reading more only when the
+	// buffered bytes could all belong to a delimiter
+	want := r.br.Buffered()
+	var peek []byte
+	var n int
+	for {
+		var err error
+		peek, err = r.br.Peek(want)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return 0, err
+		}
+		if idx := bytes.Index(peek, r.nlDashBoundary); idx >= 0 {
+			if idx == 0 {
+				p.eof = true
+				return 0, io.EOF
+			}
+			n = idx
+			break
+		}
+		if err != nil {
+			return 0, fmt.Errorf("%w: unexpected EOF in part", ErrMalformed)
+		}
+		n = len(peek) - partialDelimiter(peek, r.nlDashBoundary)
+		if n > 0 {
+			break
+		}
+		want = min(len(peek)+1, r.br.Size())
+	}
+	n = copy(b, peek[:n])
+	r.br.Discard(n)
+
+	p.n += int64(n)
+	if r.MaxPartSize > 0 && p.n > r.MaxPartSize {
+		return n, fmt.Errorf("%w: limit %d bytes", ErrPartTooLarge, r.MaxPartSize)
+	}
+	return n, r.count(n)
+}
+
+// partialDelimiter returns the length of the longest suffix of b that is a
+// proper prefix of delim.
+func partialDelimiter(b, delim []byte) int {
+	for k := min(len(b), len(delim)-1); k > 0; k-- {
+		if bytes.HasSuffix(b, delim[:k]) {
+			return k
+		}
+	}
+	return 0
+}
+
+// FormName returns the name parameter of a form-data Content-Disposition, or
+// "" if there is none.
+func (p *Part) FormName() string {
+	_, params := p.disposition()
+	return params["name"]
+}
+
+// FileName returns the filename parameter of Content-Disposition without any
+// directory components, or "" if the part is not a file.
+func (p *Part) FileName() string {
+	_, params := p.disposition()
+	name := params["filename"]
+	if name == "" {
+		return ""
+	}
+	return filepath.Base(name)
+}
+
+func (p *Part) disposition() (string, map[string]string) {
+	v, ok := p.Headers.Get("Content-Disposition")
+	if !ok {
+		return "", nil
+	}
+	disposition, params, err := mime.ParseMediaType(v)
+	if err != nil {
+		return "", nil
+	}
+	return disposition, params
+}
@@ -0,0 +1,132 @@
+package multipart
+
+import (
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testBody = "preamble\r\n" +
+	"--xyz\r\n" +
+	"Content-Disposition: form-data; name=\"title\"\r\n" +
+	"\r\n" +
+	"hello\r\n" +
+	"--xyz\r\n" +
+	"Content-Disposition: form-data; name=\"upload\"; filename=\"../../etc/notes.txt\"\r\n" +
+	"Content-Type: text/plain\r\n" +
+	"\r\n" +
+	"line one\r\n--xy not a delimiter\r\n" +
+	"--xyz--\r\n" +
+	"epilogue"
+
+func TestNextPart(t *testing.T) {
+	// Test: Parts are streamed with their headers, one byte at a time
+	r := NewReader(iotest.OneByteReader(strings.NewReader(testBody)), "xyz")
+	p, err := r.NextPart()
+	require.NoError(t, err)
+	assert.Equal(t, "title", p.FormName())
+	assert.Equal(t, "", p.FileName())
+	b, err := io.ReadAll(p)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(b))
+
+	p, err = r.NextPart()
+	require.NoError(t, err)
+	assert.Equal(t, "upload", p.FormName())
+	assert.Equal(t, "notes.txt", p.FileName())
+	contentType, _ := p.Headers.Get("Content-Type")
+	assert.Equal(t, "text/plain", contentType)
+	b, err = io.ReadAll(p)
+	require.NoError(t, err)
+	assert.Equal(t, "line one\r\n--xy not a delimiter", string(b))
+
+	_, err = r.NextPart()
+	assert.Equal(t, io.EOF, err)
+
+	// Test: Unread parts are skipped
+	r = NewReader(strings.NewReader(testBody), "xyz")
+	_, err = r.NextPart()
+	require.NoError(t, err)
+	p, err = r.NextPart()
+	require.NoError(t, err)
+	assert.Equal(t, "upload", p.FormName())
+
+	// Test: An empty filename is not a file
+	r = NewReader(strings.NewReader("--xyz\r\nContent-Disposition: form-data; name=\"f\"; filename=\"\"\r\n\r\n\r\n--xyz--\r\n"), "xyz")
+	p, err = r.NextPart()
+	require.NoError(t, err)
+	assert.Equal(t, "f", p.FormName())
+	assert.Equal(t, "", p.FileName())
+
+	// Test: The close delimiter may end the body without a CRLF
+	r = NewReader(strings.NewReader("--xyz\r\nContent-Disposition: form-data; name=\"a\"\r\n\r\n1\r\n--xyz--"), "xyz")
+	p, err = r.NextPart()
+	require.NoError(t, err)
+	b, err = io.ReadAll(p)
+	require.NoError(t, err)
+	assert.Equal(t, "1", string(b))
+	_, err = r.NextPart()
+	assert.Equal(t, io.EOF, err)
+
+	// Test: Missing closing delimiter
+	r = NewReader(strings.NewReader("--xyz\r\n\r\nabc"), "xyz")
+	p, err = r.NextPart()
+	require.NoError(t, err)
+	_, err = io.ReadAll(p)
+	require.ErrorIs(t, err, ErrMalformed)
+}
+
+func TestLimits(t *testing.T) {
+	// Test: Part size limit
+	r := NewReader(strings.NewReader(testBody), "xyz")
+	r.MaxPartSize = 10
+	_, err := r.NextPart()
+	require.NoError(t, err)
+	p, err := r.NextPart()
+	require.NoError(t, err)
+	_, err = io.ReadAll(p)
+	require.ErrorIs(t, err, ErrPartTooLarge)
+
+	// Test: Total size limit
+	r = NewReader(strings.NewReader(testBody), "xyz")
+	r.MaxTotalSize = 100
+	var last error
+	for last == nil {
+		_, last = r.NextPart()
+	}
+	require.ErrorIs(t, last, ErrMessageTooLarge)
+}
+
+func TestReadForm(t *testing.T) {
+	// Test: Everything fits in memory
+	form, err := NewReader(strings.NewReader(testBody), "xyz").ReadForm(1 << 20)
+	require.NoError(t, err)
+	defer form.RemoveAll()
+	assert.Equal(t, []string{"hello"}, form.Value["title"])
+	require.Len(t, form.File["upload"], 1)
+	fh := form.File["upload"][0]
+	assert.Equal(t, "notes.txt", fh.Filename)
+	assert.Equal(t, int64(30), fh.Size)
+	assert.Empty(t, fh.tmpfile)
+
+	// Test: Large files spill to a temp file that RemoveAll deletes
+	form, err = NewReader(strings.NewReader(testBody), "xyz").ReadForm(10)
+	require.NoError(t, err)
+	fh = form.File["upload"][0]
+	require.NotEmpty(t, fh.tmpfile)
+	f, err := fh.Open()
+	require.NoError(t, err)
+	b, err := io.ReadAll(f)
+	require.NoError(t, err)
+	f.Close()
+	assert.Equal(t, "line one\r\n--xy not a delimiter", string(b))
+	require.NoError(t, form.RemoveAll())
+	_, err = os.Stat(fh.tmpfile)
+	assert.True(t, errors.Is(err, os.ErrNotExist))
+}
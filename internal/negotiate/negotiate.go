@@ -0,0 +1,128 @@
+package negotiate
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/UUest/httpfromtcp/internal/headers"
+	"github.com/UUest/httpfromtcp/internal/response"
+)
+
+// ContentType picks the offer (a media type like "application/json") that
+// best matches the Accept header. Each offer takes the q-value of the most
+// specific range that matches it; the highest q wins, and ties go to the
+// more specific match and then to the earlier offer. Without an Accept header
+// the first offer is returned. ok is false if nothing is acceptable.
+func ContentType(h headers.Headers, offers []string) (string, bool) {
+	return best(h, "Accept", offers, matchMediaType, "")
+}
+
+// Language picks the offer (a language tag like "en-US") that best matches
+// Accept-Language, using basic filtering from RFC 4647: "en" matches "en" and
+// "en-GB" but not "eng".
+func Language(h headers.Headers, offers []string) (string, bool) {
+	return best(h, "Accept-Language", offers, matchLanguage, "")
+}
+
+// Encoding picks the offer (a content coding like "gzip" or "identity") that
+// best matches Accept-Encoding. identity is acceptable unless it is excluded
+// explicitly or with "*;q=0".
+func Encoding(h headers.Headers, offers []string) (string, bool) {
+	return best(h, "Accept-Encoding", offers, matchEncoding, "identity")
+}
+
+// matchFunc returns how specifically accept matches offer, or -1 if it does
+// not match at all.
+type matchFunc func(accept headers.QualityValue, offer string) int
+
+// best returns the winning offer. implicit names an offer that stays
+// acceptable, at the lowest priority, unless some range matches it.
+func best(h headers.Headers, key string, offers []string, match matchFunc, implicit string) (string, bool) {
+	if len(offers) == 0 {
+		return "", false
+	}
+	if _, ok := h.Get(key); !ok {
+		return offers[0], true
+	}
+	accepts := h.QualityValues(key)
+
+	bestOffer, bestQ, bestSpecificity := "", 0.0, -1
+	for _, offer := range offers {
+		q, specificity := 0.0, -1
+		for _, accept := range accepts {
+			s := match(accept, offer)
+			if s > specificity {
+				q, specificity = accept.Q, s
+			}
+		}
+		if specificity == -1 && strings.EqualFold(offer, implicit) {
+			q, specificity = 0.001, 0
+		}
+		if q > bestQ || (q == bestQ && q > 0 && specificity > bestSpecificity) {
+			bestOffer, bestQ, bestSpecificity = offer, q, specificity
+		}
+	}
+	return bestOffer, bestQ > 0
+}
+
+func matchMediaType(accept headers.QualityValue, offer string) int {
+	acceptType, acceptSub, ok := strings.Cut(accept.Value, "/")
+	if !ok {
+		return -1
+	}
+	offerType, offerSub, ok := strings.Cut(strings.ToLower(offer), "/")
+	if !ok {
+		return -1
+	}
+	switch {
+	case acceptType == "*" && acceptSub == "*":
+		return 0
+	case acceptType != offerType:
+		return -1
+	case acceptSub == "*":
+		return 1
+	case acceptSub != offerSub:
+		return -1
+	}
+	// parameters in the range make the match more specific
+	return 2 + len(accept.Params)
+}
+
+func matchLanguage(accept headers.QualityValue, offer string) int {
+	offer = strings.ToLower(offer)
+	switch {
+	case accept.Value == "*":
+		return 0
+	case accept.Value == offer:
+		return len(accept.Value) + 1
+	case strings.HasPrefix(offer, accept.Value+"-"):
+		return len(accept.Value)
+	}
+	return -1
+}
+
+func matchEncoding(accept headers.QualityValue, offer string) int {
+	switch {
+	case accept.Value == "*":
+		return 0
+	case accept.Value == strings.ToLower(offer):
+		return 1
+	}
+	return -1
+}
+
+// WriteNotAcceptable answers 406 and lists the representations the client
+// could have asked for.
+func WriteNotAcceptable(w *response.Writer, offers []string) error {
+	err := w.WriteStatusLine(response.StatusCodeNotAcceptable)
+	if err != nil {
+		return err
+	}
+	body := []byte(fmt.Sprintf("None of the available representations are acceptable: %s\n", strings.Join(offers, ", ")))
+	err = w.WriteHeaders(response.GetDefaultHeaders(len(body)))
+	if err != nil {
+		return err
+	}
+	_, err = w.WriteBody(body)
+	return err
+}
@@ -0,0 +1,94 @@
+package negotiate
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/UUest/httpfromtcp/internal/headers"
+	"github.com/UUest/httpfromtcp/internal/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func withHeader(key, value string) headers.Headers {
+	h := headers.NewHeaders()
+	h.Set(key, value)
+	return h
+}
+
+func TestContentType(t *testing.T) {
+	offers := []string{"text/html", "application/json"}
+
+	// Test: No Accept header picks the first offer
+	got, ok := ContentType(headers.NewHeaders(), offers)
+	assert.True(t, ok)
+	assert.Equal(t, "text/html", got)
+
+	// Test: API client
+	got, ok = ContentType(withHeader("Accept", "application/json"), offers)
+	assert.True(t, ok)
+	assert.Equal(t, "application/json", got)
+
+	// Test: Browser-style header with q-values
+	got, ok = ContentType(withHeader("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8"), offers)
+	assert.True(t, ok)
+	assert.Equal(t, "text/html", got)
+
+	// Test: More specific range overrides a wildcard
+	got, ok = ContentType(withHeader("Accept", "*/*;q=0.9, text/html;q=0.1"), offers)
+	assert.True(t, ok)
+	assert.Equal(t, "application/json", got)
+
+	// Test: Type wildcard
+	got, ok = ContentType(withHeader("Accept", "application/*"), offers)
+	assert.True(t, ok)
+	assert.Equal(t, "application/json", got)
+
+	// Test: Nothing acceptable
+	_, ok = ContentType(withHeader("Accept", "image/png, text/html;q=0"), offers)
+	assert.False(t, ok)
+}
+
+func TestLanguage(t *testing.T) {
+	offers := []string{"en-US", "de", "fr-CA"}
+
+	// Test: Prefix match
+	got, ok := Language(withHeader("Accept-Language", "fr, en;q=0.5"), offers)
+	assert.True(t, ok)
+	assert.Equal(t, "fr-CA", got)
+
+	// Test: Prefix must end at a subtag boundary
+	_, ok = Language(withHeader("Accept-Language", "d"), offers)
+	assert.False(t, ok)
+
+	// Test: Wildcard
+	got, ok = Language(withHeader("Accept-Language", "ja, *;q=0.1"), offers)
+	assert.True(t, ok)
+	assert.Equal(t, "en-US", got)
+}
+
+func TestEncoding(t *testing.T) {
+	offers := []string{"br", "gzip", "identity"}
+
+	// Test: Highest q wins
+	got, ok := Encoding(withHeader("Accept-Encoding", "gzip;q=1.0, br;q=0.5"), offers)
+	assert.True(t, ok)
+	assert.Equal(t, "gzip", got)
+
+	// Test: identity is implicitly acceptable
+	got, ok = Encoding(withHeader("Accept-Encoding", "compress"), offers)
+	assert.True(t, ok)
+	assert.Equal(t, "identity", got)
+
+	// Test: identity excluded by a wildcard
+	_, ok = Encoding(withHeader("Accept-Encoding", "*;q=0"), offers)
+	assert.False(t, ok)
+}
+
+func TestWriteNotAcceptable(t *testing.T) {
+	// Test: 406 lists the offers
+	var out strings.Builder
+	require.NoError(t, WriteNotAcceptable(response.NewWriter(&out), []string{"text/html", "application/json"}))
+	assert.True(t, strings.HasPrefix(out.String(), "HTTP/1.1 406 Not Acceptable\r\n"))
+	assert.Contains(t, out.String(), "text/html, application/json")
+}
@@ -0,0 +1,145 @@
+package request
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/UUest/httpfromtcp/internal/headers"
+)
+
+var (
+	ErrInvalidContentLength              = headers.ErrInvalidContentLength
+	ErrConflictingContentLength          = headers.ErrConflictingContentLength
+	ErrContentLengthWithTransferEncoding = errors.New("both Transfer-Encoding and Content-Length present")
+	ErrUnsupportedTransferEncoding       = errors.New("unsupported Transfer-Encoding")
+	ErrObsFold                           = headers.ErrObsFold
+	ErrBareLF                            = errors.New("bare LF line ending")
+	ErrInvalidChunk                      = errors.New("invalid chunked encoding")
+)
+
+type chunkState int
+
+const (
+	chunkStateSize chunkState = iota
+	chunkStateData
+	chunkStateDataEnd
+	chunkStateTrailers
+)
+
+// lineEnd returns the index of the CRLF that ends the first line in data, or
+// -1 if no full line has arrived yet. A LF without a preceding CR is an error
+// rather than a line ending, so that we never disagree with a stricter proxy
+// about where a line ends.
+func lineEnd(data []byte) (int, error) {
+	idx := bytes.IndexByte(data, '\n')
+	if idx == -1 {
+		return -1, nil
+	}
+	if idx == 0 || data[idx-1] != '\r' {
+		return -1, ErrBareLF
+	}
+	return idx - 1, nil
+}
+
+// resolveFraming decides how the body is delimited once all headers are in.
+// Anything ambiguous is rejected, since a front-end that resolves the
+// ambiguity differently could smuggle a second request in the body.
+func (r *Request) resolveFraming() error {
+	r.contentLength = -1
+	te, hasTE := r.Headers.Get("Transfer-Encoding")
+	_, hasCL := r.Headers.Get("Content-Length")
+	if hasTE && hasCL {
+		return ErrContentLengthWithTransferEncoding
+	}
+
+	if hasTE {
+		if r.RequestLine.HttpVersion == "1.0" {
+			return fmt.Errorf("%w: Transfer-Encoding in an HTTP/1.0 request", ErrUnsupportedTransferEncoding)
+		}
+		// chunked is the only coding we can decode, and it must appear once
+		if !strings.EqualFold(strings.TrimSpace(te), "chunked") {
+			return fmt.Errorf("%w: %s", ErrUnsupportedTransferEncoding, te)
+		}
+		r.chunked = true
+		return nil
+	}
+
+	// duplicate headers are merged into a list; ContentLength accepts
+	// identical values and rejects differing ones
+	length, ok, err := r.Headers.ContentLength()
+	if err != nil {
+		return err
+	}
+	if ok {
+		r.contentLength = length
+	}
+	return nil
+}
+
+func (r *Request) parseChunked(data []byte) (int, error) {
+	switch r.chunkState {
+	case chunkStateSize:
+		idx, err := lineEnd(data)
+		if err != nil || idx == -1 {
+			return 0, err
+		}
+		line := string(data[:idx])
+		sizeStr, _, _ := strings.Cut(line, ";")
+		sizeStr = strings.TrimRight(sizeStr, " \t")
+		size, err := strconv.ParseUint(sizeStr, 16, 63)
+		if err != nil {
+			return 0, fmt.Errorf("%w: bad chunk size %q", ErrInvalidChunk, line)
+		}
+		r.chunkRemaining = int64(size)
+		r.chunkState = chunkStateData
+		if size == 0 {
+			r.chunkState = chunkStateTrailers
+		}
+		return idx + 2, nil
+	case chunkStateData:
+		n := int64(len(data))
+		if n > r.chunkRemaining {
+			n = r.chunkRemaining
+		}
+		r.Body = append(r.Body, data[:n]...)
+		r.bodyLengthRead += int(n)
+		r.chunkRemaining -= n
+		if r.chunkRemaining == 0 {
+			r.chunkState = chunkStateDataEnd
+		}
+		return int(n), nil
+	case chunkStateDataEnd:
+		if len(data) < 2 {
+			return 0, nil
+		}
+		if !bytes.HasPrefix(data, []byte(crlf)) {
+			return 0, fmt.Errorf("%w: missing CRLF after chunk data", ErrInvalidChunk)
+		}
+		r.chunkState = chunkStateSize
+		return 2, nil
+	case chunkStateTrailers:
+		n, done, err := parseFieldLine(r.trailerParser, data)
+		if err != nil {
+			return 0, err
+		}
+		if done {
+			r.state = requestStateDone
+		}
+		return n, nil
+	default:
+		return 0, fmt.Errorf("unknown chunk state")
+	}
+}
+
+// parseFieldLine rejects bare LF line endings before handing the next line to
+// p, which only looks for CRLF.
+func parseFieldLine(p *headers.Parser, data []byte) (int, bool, error) {
+	idx, err := lineEnd(data)
+	if err != nil || idx == -1 {
+		return 0, false, err
+	}
+	return p.Parse(data)
+}
@@ -0,0 +1,24 @@
+package request
+
+import (
+	"github.com/UUest/httpfromtcp/internal/cookie"
+)
+
+// Cookies parses the Cookie header sent with the request.
+func (r *Request) Cookies() []*cookie.Cookie {
+	v, ok := r.Headers.Get("Cookie")
+	if !ok {
+		return nil
+	}
+	return cookie.Parse(v)
+}
+
+// Cookie returns the first cookie with the given name.
+func (r *Request) Cookie(name string) (*cookie.Cookie, bool) {
+	for _, c := range r.Cookies() {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,83 @@
+package request
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+)
+
+// DefaultMaxFormSize caps the urlencoded body ParseForm will accept.
+const DefaultMaxFormSize = 10 << 20
+
+var (
+	ErrFormTooLarge    = errors.New("form body too large")
+	ErrFormContentType = errors.New("body is not application/x-www-form-urlencoded")
+	ErrInvalidForm     = errors.New("invalid form encoding")
+)
+
+// ParseForm fills PostForm from an application/x-www-form-urlencoded body
+// and Form from both the query string and the body, with body values listed
+// first. The body is read if it has not been already. It is limited to
+// DefaultMaxFormSize; see ParseFormLimit.
+func (r *Request) ParseForm() error {
+	return r.ParseFormLimit(DefaultMaxFormSize)
+}
+
+// ParseFormLimit is ParseForm with a custom body size limit. A request with a
+// body of any other Content-Type returns ErrFormContentType, but Form still
+// holds the query values. Only the first call does any work. Under Serve the
+// body is usually read before the handler runs, so how much is read is
+// bounded by server.WithMaxBodySize; maxSize is still enforced.
+func (r *Request) ParseFormLimit(maxSize int64) error {
+	if r.Form != nil {
+		return nil
+	}
+	r.PostForm = url.Values{}
+	form := url.Values{}
+	defer func() {
+		for k, vs := range r.Target.Query {
+			form[k] = append(form[k], vs...)
+		}
+		r.Form = form
+	}()
+
+	if !r.chunked && r.contentLength <= 0 {
+		return nil
+	}
+	mediaType, _, err := r.Headers.ContentType()
+	if err != nil || mediaType != "application/x-www-form-urlencoded" {
+		return fmt.Errorf("%w: %q", ErrFormContentType, mediaType)
+	}
+	if r.contentLength > maxSize {
+		return fmt.Errorf("%w: %d bytes, limit %d", ErrFormTooLarge, r.contentLength, maxSize)
+	}
+	body, err := r.ReadBodyLimit(maxSize)
+	if errors.Is(err, ErrBodyTooLarge) {
+		return fmt.Errorf("%w: limit %d", ErrFormTooLarge, maxSize)
+	}
+	if err != nil {
+		return err
+	}
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidForm, err)
+	}
+	r.PostForm = values
+	for k, vs := range values {
+		form[k] = append(form[k], vs...)
+	}
+	return nil
+}
+
+// FormValue returns the first value for key from Form, parsing the form if
+// needed. Parse errors are ignored; call ParseForm to see them.
+func (r *Request) FormValue(key string) string {
+	r.ParseForm()
+	return r.Form.Get(key)
+}
+
+// PostFormValue is FormValue restricted to the body.
+func (r *Request) PostFormValue(key string) string {
+	r.ParseForm()
+	return r.PostForm.Get(key)
+}
@@ -0,0 +1,153 @@
+package request
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseForm(t *testing.T) {
+	// Test: Query and body values are merged, body first
+	reader := &chunkReader{
+		data: "POST /submit?name=query&page=2 HTTP/1.1\r\n" +
+			"Host: localhost:42069\r\n" +
+			"Content-Type: application/x-www-form-urlencoded\r\n" +
+			"Content-Length: 24\r\n" +
+			"\r\n" +
+			"name=body&msg=hello+w%21",
+		numBytesPerRead: 3,
+	}
+	r, err := HeadersFromReader(reader)
+	require.NoError(t, err)
+	require.NoError(t, r.ParseForm())
+	assert.Equal(t, []string{"body", "query"}, r.Form["name"])
+	assert.Equal(t, "2", r.FormValue("page"))
+	assert.Equal(t, "hello w!", r.PostFormValue("msg"))
+	assert.Empty(t, r.PostFormValue("page"))
+
+	// Test: No body only uses the query
+	reader = &chunkReader{
+		data:            "GET /search?q=go HTTP/1.1\r\nHost: localhost:42069\r\n\r\n",
+		numBytesPerRead: 3,
+	}
+	r, err = RequestFromReader(reader)
+	require.NoError(t, err)
+	require.NoError(t, r.ParseForm())
+	assert.Equal(t, "go", r.Form.Get("q"))
+	assert.Empty(t, r.PostForm)
+
+	// Test: Wrong Content-Type
+	reader = &chunkReader{
+		data:            "POST /submit?q=go HTTP/1.1\r\nHost: localhost:42069\r\nContent-Type: application/json\r\nContent-Length: 2\r\n\r\n{}",
+		numBytesPerRead: 3,
+	}
+	r, err = RequestFromReader(reader)
+	require.NoError(t, err)
+	require.ErrorIs(t, r.ParseForm(), ErrFormContentType)
+	assert.Equal(t, "go", r.Form.Get("q"))
+
+	// Test: Bad percent-encoding
+	reader = &chunkReader{
+		data:            "POST / HTTP/1.1\r\nHost: localhost:42069\r\nContent-Type: application/x-www-form-urlencoded\r\nContent-Length: 5\r\n\r\na=%zz",
+		numBytesPerRead: 3,
+	}
+	r, err = RequestFromReader(reader)
+	require.NoError(t, err)
+	require.ErrorIs(t, r.ParseForm(), ErrInvalidForm)
+
+	// Test: Body over the limit is rejected before it is read
+	reader = &chunkReader{
+		data:            "POST / HTTP/1.1\r\nHost: localhost:42069\r\nContent-Type: application/x-www-form-urlencoded\r\nContent-Length: 11\r\n\r\na=123456789",
+		numBytesPerRead: 3,
+	}
+	r, err = HeadersFromReader(reader)
+	require.NoError(t, err)
+	require.ErrorIs(t, r.ParseFormLimit(10), ErrFormTooLarge)
+
+	// Test: Chunked body fails as soon as it goes over the limit
+	r, err = HeadersFromReader(io.MultiReader(
+		strings.NewReader("POST / HTTP/1.1\r\nHost: localhost:42069\r\nContent-Type: application/x-www-form-urlencoded\r\nTransfer-Encoding: chunked\r\n\r\nb\r\na=123456789\r\n"),
+		iotest.ErrReader(errors.New("read past the limit")),
+	))
+	require.NoError(t, err)
+	require.ErrorIs(t, r.ParseFormLimit(10), ErrFormTooLarge)
+
+	// Test: Empty body without Content-Type only uses the query
+	reader = &chunkReader{
+		data:            "POST /submit?q=go HTTP/1.1\r\nHost: localhost:42069\r\nContent-Length: 0\r\n\r\n",
+		numBytesPerRead: 3,
+	}
+	r, err = RequestFromReader(reader)
+	require.NoError(t, err)
+	require.NoError(t, r.ParseForm())
+	assert.Equal(t, url.Values{"q": {"go"}}, r.Form)
+	assert.Empty(t, r.PostForm)
+}
+
+func TestMultipartReader(t *testing.T) {
+	// Test: Boundary comes from Content-Type
+	body := "--b1\r\nContent-Disposition: form-data; name=\"a\"\r\n\r\n1\r\n--b1--\r\n"
+	reader := &chunkReader{
+		data: "POST /upload HTTP/1.1\r\n" +
+			"Host: localhost:42069\r\n" +
+			"Content-Type: multipart/form-data; boundary=b1\r\n" +
+			fmt.Sprintf("Content-Length: %d\r\n", len(body)) +
+			"\r\n" + body,
+		numBytesPerRead: 3,
+	}
+	r, err := HeadersFromReader(reader)
+	require.NoError(t, err)
+	mr, err := r.MultipartReader()
+	require.NoError(t, err)
+	form, err := mr.ReadForm(1 << 20)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1"}, form.Value["a"])
+	_, err = r.ReadBody()
+	require.ErrorIs(t, err, ErrBodyStreamed)
+
+	// Test: Parts are read before the rest of the body arrives
+	pr, pw := io.Pipe()
+	firstRead := make(chan struct{})
+	go func() {
+		io.WriteString(pw, "POST /upload HTTP/1.1\r\n"+
+			"Host: localhost:42069\r\n"+
+			"Content-Type: multipart/form-data; boundary=b1\r\n"+
+			"Transfer-Encoding: chunked\r\n\r\n")
+		io.WriteString(pw, "32\r\n--b1\r\nContent-Disposition: form-data; name=\"a\"\r\n\r\n\r\n")
+		io.WriteString(pw, "6\r\nfirst\n\r\n")
+		<-firstRead
+		io.WriteString(pw, "a\r\n\r\n--b1--\r\n\r\n0\r\n\r\n")
+		pw.Close()
+	}()
+	r, err = HeadersFromReader(pr)
+	require.NoError(t, err)
+	mr, err = r.MultipartReader()
+	require.NoError(t, err)
+	part, err := mr.NextPart()
+	require.NoError(t, err)
+	assert.Equal(t, "a", part.FormName())
+	line, err := bufio.NewReader(part).ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "first\n", line)
+	close(firstRead)
+	_, err = mr.NextPart()
+	require.ErrorIs(t, err, io.EOF)
+
+	// Test: Not multipart
+	reader = &chunkReader{
+		data:            "POST / HTTP/1.1\r\nHost: localhost:42069\r\nContent-Type: text/plain\r\nContent-Length: 0\r\n\r\n",
+		numBytesPerRead: 3,
+	}
+	r, err = HeadersFromReader(reader)
+	require.NoError(t, err)
+	_, err = r.MultipartReader()
+	require.ErrorIs(t, err, ErrNotMultipart)
+}
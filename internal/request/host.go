@@ -0,0 +1,103 @@
+package request
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrInvalidHost = errors.New("invalid Host")
+
+// resolveHost sets r.Host from the request-target or the Host header. HTTP/1.1
+// requests must carry exactly one Host header; when the target is in
+// absolute-form its authority wins over the header, as RFC 9112 section 3.2.2
+// requires.
+func (r *Request) resolveHost() error {
+	host, ok := r.Headers.Get("Host")
+	if !ok && r.RequestLine.HttpVersion == "1.1" {
+		return fmt.Errorf("%w: missing Host header", ErrInvalidHost)
+	}
+	if strings.Contains(host, ",") {
+		return fmt.Errorf("%w: multiple Host headers", ErrInvalidHost)
+	}
+	if ok && !validHost(host) {
+		return fmt.Errorf("%w: %q", ErrInvalidHost, host)
+	}
+
+	switch r.Target.Form {
+	case TargetAbsoluteForm, TargetAuthorityForm:
+		if !validHost(r.Target.Host) {
+			return fmt.Errorf("%w: %q in request-target", ErrInvalidHost, r.Target.Host)
+		}
+		host = r.Target.Host
+	}
+	r.Host = strings.ToLower(host)
+	return nil
+}
+
+// validHost checks host against uri-host [ ":" port ] from RFC 3986. An empty
+// value is allowed for targets without an authority.
+func validHost(host string) bool {
+	if host == "" {
+		return true
+	}
+	name, port := host, ""
+	if strings.HasPrefix(host, "[") {
+		end := strings.IndexByte(host, ']')
+		if end == -1 {
+			return false
+		}
+		name, port = host[:end+1], host[end+1:]
+		if port != "" && port[0] != ':' {
+			return false
+		}
+		port = strings.TrimPrefix(port, ":")
+		if !validIPLiteral(name[1:end]) {
+			return false
+		}
+	} else if idx := strings.LastIndexByte(host, ':'); idx != -1 {
+		name, port = host[:idx], host[idx+1:]
+		if !validRegName(name) {
+			return false
+		}
+	} else if !validRegName(name) {
+		return false
+	}
+	for i := 0; i < len(port); i++ {
+		if !isDigit(port[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func validRegName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', isDigit(c):
+		case strings.IndexByte("-._~!$&'()*+;=%", c) != -1:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func validIPLiteral(addr string) bool {
+	if addr == "" {
+		return false
+	}
+	for i := 0; i < len(addr); i++ {
+		c := addr[i]
+		switch {
+		case isDigit(c), c >= 'a' && c <= 'f', c >= 'A' && c <= 'F', c == ':', c == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
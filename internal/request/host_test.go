@@ -0,0 +1,70 @@
+package request
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHostValidation(t *testing.T) {
+	// Test: Host header is lowercased into Request.Host
+	reader := &chunkReader{
+		data:            "GET / HTTP/1.1\r\nHost: LocalHost:42069\r\n\r\n",
+		numBytesPerRead: 3,
+	}
+	r, err := RequestFromReader(reader)
+	require.NoError(t, err)
+	assert.Equal(t, "localhost:42069", r.Host)
+
+	// Test: IPv6 literal
+	reader = &chunkReader{
+		data:            "GET / HTTP/1.1\r\nHost: [::1]:8080\r\n\r\n",
+		numBytesPerRead: 3,
+	}
+	r, err = RequestFromReader(reader)
+	require.NoError(t, err)
+	assert.Equal(t, "[::1]:8080", r.Host)
+
+	// Test: Missing Host in HTTP/1.1
+	reader = &chunkReader{
+		data:            "GET / HTTP/1.1\r\n\r\n",
+		numBytesPerRead: 3,
+	}
+	_, err = RequestFromReader(reader)
+	require.ErrorIs(t, err, ErrInvalidHost)
+
+	// Test: Missing Host in HTTP/1.0 is fine
+	reader = &chunkReader{
+		data:            "GET / HTTP/1.0\r\n\r\n",
+		numBytesPerRead: 3,
+	}
+	r, err = RequestFromReader(reader)
+	require.NoError(t, err)
+	assert.Equal(t, "", r.Host)
+
+	// Test: Duplicate Host headers
+	reader = &chunkReader{
+		data:            "GET / HTTP/1.1\r\nHost: localhost:42069\r\nHost: localhost:42069\r\n\r\n",
+		numBytesPerRead: 4,
+	}
+	_, err = RequestFromReader(reader)
+	require.ErrorIs(t, err, ErrInvalidHost)
+
+	// Test: Invalid Host value
+	reader = &chunkReader{
+		data:            "GET / HTTP/1.1\r\nHost: local/host:port\r\n\r\n",
+		numBytesPerRead: 4,
+	}
+	_, err = RequestFromReader(reader)
+	require.ErrorIs(t, err, ErrInvalidHost)
+
+	// Test: Absolute-form target overrides the Host header
+	reader = &chunkReader{
+		data:            "GET http://Example.com/coffee HTTP/1.1\r\nHost: other.example\r\n\r\n",
+		numBytesPerRead: 4,
+	}
+	r, err = RequestFromReader(reader)
+	require.NoError(t, err)
+	assert.Equal(t, "example.com", r.Host)
+}
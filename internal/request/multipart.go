@@ -0,0 +1,26 @@
+package request
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/UUest/httpfromtcp/internal/multipart"
+)
+
+var ErrNotMultipart = errors.New("request is not multipart")
+
+// MultipartReader returns a multipart.Reader for a multipart/* body, using
+// the boundary from Content-Type. Parts are read from the connection as the
+// caller asks for them, so after this ReadBody returns ErrBodyStreamed.
+func (r *Request) MultipartReader() (*multipart.Reader, error) {
+	mediaType, params, err := r.Headers.ContentType()
+	if err != nil || !strings.HasPrefix(mediaType, "multipart/") {
+		return nil, fmt.Errorf("%w: Content-Type %q", ErrNotMultipart, mediaType)
+	}
+	boundary := params["boundary"]
+	if boundary == "" || len(boundary) > 70 {
+		return nil, fmt.Errorf("%w: invalid boundary %q", ErrNotMultipart, boundary)
+	}
+	return multipart.NewReader(r.BodyReader(), boundary), nil
+}
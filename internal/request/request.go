@@ -5,7 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"strconv"
+	"net/url"
 	"strings"
 
 	"github.com/UUest/httpfromtcp/internal/headers"
@@ -13,11 +13,30 @@ import (
 
 type Request struct {
 	RequestLine RequestLine
+	Target      Target
+	Host        string
 	Headers     headers.Headers
 	Body        []byte
+	Trailers    headers.Headers
+
+	// Form and PostForm are nil until ParseForm is called.
+	Form     url.Values
+	PostForm url.Values
 
 	state          requestState
 	bodyLengthRead int
+	contentLength  int64
+	chunked        bool
+	chunkState     chunkState
+	chunkRemaining int64
+
+	headerParser   *headers.Parser
+	trailerParser  *headers.Parser
+	reader         io.Reader
+	buf            []byte
+	readToIndex    int
+	beforeBodyRead func() error
+	bodyStreamed   bool
 }
 
 type RequestLine struct {
@@ -35,49 +54,215 @@ const (
 	requestStateDone
 )
 
+// ErrUnsupportedVersion is returned for well-formed HTTP-versions other than
+// 1.0 and 1.1, which should be answered with 505.
+var ErrUnsupportedVersion = errors.New("unsupported HTTP-version")
+
+// ErrBodyStreamed is returned by ReadBody once the body has been handed out
+// through BodyReader.
+var ErrBodyStreamed = errors.New("request body was consumed through BodyReader")
+
+// ErrBodyTooLarge is returned by ReadBodyLimit once the body grows past the
+// limit.
+var ErrBodyTooLarge = errors.New("request body too large")
+
 const crlf = "\r\n"
 const bufferSize = 8
 
 func RequestFromReader(reader io.Reader) (*Request, error) {
-	buf := make([]byte, bufferSize)
-	readToIndex := 0
+	req, err := HeadersFromReader(reader)
+	if err != nil {
+		return nil, err
+	}
+	_, err = req.ReadBody()
+	if err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// Options relax the parser for clients that need it. The zero value is the
+// strict default.
+type Options struct {
+	// ObsFold decides whether folded header lines are rejected or unfolded.
+	ObsFold headers.FoldMode
+}
+
+// HeadersFromReader parses the request-line and headers and leaves the body
+// unread, so the caller can decide whether and when to read it with ReadBody.
+func HeadersFromReader(reader io.Reader) (*Request, error) {
+	return HeadersFromReaderWithOptions(reader, Options{})
+}
+
+func HeadersFromReaderWithOptions(reader io.Reader, opts Options) (*Request, error) {
 	req := &Request{
-		state:   requestStateInitialized,
-		Headers: headers.NewHeaders(),
-		Body:    make([]byte, 0),
+		state:    requestStateInitialized,
+		Headers:  headers.NewHeaders(),
+		Body:     make([]byte, 0),
+		Trailers: headers.NewHeaders(),
+		reader:   reader,
+		buf:      make([]byte, bufferSize),
 	}
-	for req.state != requestStateDone {
-		if readToIndex >= len(buf) {
-			newBuf := make([]byte, len(buf)*2)
-			copy(newBuf, buf)
-			buf = newBuf
-		}
+	req.headerParser = headers.NewParser(req.Headers, opts.ObsFold)
+	req.trailerParser = headers.NewParser(req.Trailers, opts.ObsFold)
+	err := req.readUntil(requestStateParsingBody)
+	if err != nil {
+		return nil, err
+	}
+	return req, nil
+}
 
-		numBytesRead, err := reader.Read(buf[readToIndex:])
+// ReadBody reads the rest of the request and returns the body. It is safe to
+// call more than once.
+func (r *Request) ReadBody() ([]byte, error) {
+	if r.bodyStreamed {
+		return nil, ErrBodyStreamed
+	}
+	err := r.readUntil(requestStateDone)
+	if err != nil {
+		return nil, err
+	}
+	return r.Body, nil
+}
+
+// BeforeBodyRead registers fn to run once, right before the first read of
+// body bytes from the underlying reader. Bodies that already arrived along
+// with the headers do not trigger it.
+func (r *Request) BeforeBodyRead(fn func() error) {
+	r.beforeBodyRead = fn
+}
+
+// ReadBodyLimit is ReadBody for bodies of at most maxSize bytes. It stops
+// reading and returns ErrBodyTooLarge as soon as the body goes over, which
+// matters for chunked bodies whose length is not known up front.
+func (r *Request) ReadBodyLimit(maxSize int64) ([]byte, error) {
+	if r.bodyStreamed {
+		return nil, ErrBodyStreamed
+	}
+	for {
+		done, err := r.parseBuffered(requestStateDone)
 		if err != nil {
-			if errors.Is(err, io.EOF) {
-				if req.state != requestStateDone {
-					return nil, fmt.Errorf("incomplete request, in state: %d, read n bytes on EOF: %d", req.state, numBytesRead)
-				}
-				break
-			}
 			return nil, err
 		}
-		readToIndex += numBytesRead
-
-		numBytesParsed, err := req.parse(buf[:readToIndex])
+		if int64(len(r.Body)) > maxSize {
+			return nil, fmt.Errorf("%w: limit %d bytes", ErrBodyTooLarge, maxSize)
+		}
+		if done {
+			return r.Body, nil
+		}
+		err = r.readMore()
 		if err != nil {
 			return nil, err
 		}
+	}
+}
+
+// ExpectsContinue reports whether the client sent Expect: 100-continue.
+func (r *Request) ExpectsContinue() bool {
+	expect, ok := r.Headers.Get("Expect")
+	return ok && strings.EqualFold(expect, "100-continue")
+}
 
-		copy(buf, buf[numBytesParsed:])
-		readToIndex -= numBytesParsed
+// BodyReader returns the body as a stream read straight from the
+// connection, so large bodies need not fit in memory. The bytes are not kept
+// in Body, and ReadBody fails afterwards. If the body was already read with
+// ReadBody, the reader returns Body.
+func (r *Request) BodyReader() io.Reader {
+	if r.state == requestStateDone && !r.bodyStreamed {
+		return bytes.NewReader(r.Body)
 	}
-	return req, nil
+	r.bodyStreamed = true
+	return &bodyReader{r: r}
+}
+
+type bodyReader struct {
+	r *Request
+}
+
+func (b *bodyReader) Read(p []byte) (int, error) {
+	r := b.r
+	for len(r.Body) == 0 {
+		if r.state == requestStateDone {
+			return 0, io.EOF
+		}
+		_, err := r.parseBuffered(requestStateDone)
+		if err != nil {
+			return 0, err
+		}
+		if len(r.Body) > 0 || r.state == requestStateDone {
+			break
+		}
+		err = r.readMore()
+		if err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, r.Body)
+	r.Body = r.Body[n:]
+	if len(r.Body) == 0 {
+		r.Body = r.Body[:0]
+	}
+	return n, nil
+}
+
+func (r *Request) readUntil(state requestState) error {
+	for {
+		done, err := r.parseBuffered(state)
+		if err != nil || done {
+			return err
+		}
+		err = r.readMore()
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// parseBuffered parses whatever is buffered and reports whether the request
+// reached state.
+func (r *Request) parseBuffered(state requestState) (bool, error) {
+	numBytesParsed, err := r.parse(r.buf[:r.readToIndex], state)
+	if err != nil {
+		return false, err
+	}
+	copy(r.buf, r.buf[numBytesParsed:r.readToIndex])
+	r.readToIndex -= numBytesParsed
+	return r.state >= state, nil
+}
+
+// readMore reads once from the underlying reader into the buffer.
+func (r *Request) readMore() error {
+	if r.readToIndex >= len(r.buf) {
+		newBuf := make([]byte, len(r.buf)*2)
+		copy(newBuf, r.buf)
+		r.buf = newBuf
+	}
+
+	if r.state == requestStateParsingBody && r.beforeBodyRead != nil {
+		hook := r.beforeBodyRead
+		r.beforeBodyRead = nil
+		err := hook()
+		if err != nil {
+			return err
+		}
+	}
+
+	numBytesRead, err := r.reader.Read(r.buf[r.readToIndex:])
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return fmt.Errorf("incomplete request, in state: %d, read n bytes on EOF: %d", r.state, numBytesRead)
+		}
+		return err
+	}
+	r.readToIndex += numBytesRead
+	return nil
 }
 
 func parseRequestLine(data []byte) (*RequestLine, int, error) {
-	idx := bytes.Index(data, []byte(crlf))
+	idx, err := lineEnd(data)
+	if err != nil {
+		return nil, 0, err
+	}
 	if idx == -1 {
 		return nil, 0, nil
 	}
@@ -114,9 +299,12 @@ func requestLineFromString(str string) (*RequestLine, error) {
 		return nil, fmt.Errorf("unrecognized HTTP-version: %s", httpPart)
 	}
 	version := versionParts[1]
-	if version != "1.1" {
+	if len(version) != 3 || !isDigit(version[0]) || version[1] != '.' || !isDigit(version[2]) {
 		return nil, fmt.Errorf("unrecognized HTTP-version: %s", version)
 	}
+	if version != "1.1" && version != "1.0" {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedVersion, version)
+	}
 
 	return &RequestLine{
 		Method:        method,
@@ -125,9 +313,15 @@ func requestLineFromString(str string) (*RequestLine, error) {
 	}, nil
 }
 
-func (r *Request) parse(data []byte) (int, error) {
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// parse consumes data until the request reaches the until state or more data
+// is needed.
+func (r *Request) parse(data []byte, until requestState) (int, error) {
 	totalBytesParsed := 0
-	for r.state != requestStateDone {
+	for r.state < until {
 		n, err := r.parseSingle(data[totalBytesParsed:])
 		if err != nil {
 			return 0, err
@@ -152,29 +346,41 @@ func (r *Request) parseSingle(data []byte) (int, error) {
 			// need more data so nil returned instead of err
 			return 0, nil
 		}
+		target, err := ParseTarget(requestLine.Method, requestLine.RequestTarget)
+		if err != nil {
+			return 0, err
+		}
 		r.RequestLine = *requestLine
+		r.Target = target
 		r.state = requestStateParsingHeaders
 		return n, nil
 	case requestStateParsingHeaders:
-		n, done, err := r.Headers.Parse(data)
+		n, done, err := parseFieldLine(r.headerParser, data)
 		if err != nil {
 			return 0, err
 		}
 		if done {
+			err = r.resolveHost()
+			if err != nil {
+				return 0, err
+			}
+			err = r.resolveFraming()
+			if err != nil {
+				return 0, err
+			}
 			r.state = requestStateParsingBody
 		}
 		return n, nil
 	case requestStateParsingBody:
-		contentLenStr, ok := r.Headers.Get("Content-Length")
-		if !ok {
+		if r.chunked {
+			return r.parseChunked(data)
+		}
+		if r.contentLength == -1 {
 			// assume that if no content-length header is present, there is no body
 			r.state = requestStateDone
 			return len(data), nil
 		}
-		contentLen, err := strconv.Atoi(contentLenStr)
-		if err != nil {
-			return 0, fmt.Errorf("malformed Content-Length: %s", err)
-		}
+		contentLen := int(r.contentLength)
 		r.Body = append(r.Body, data...)
 		r.bodyLengthRead += len(data)
 		if r.bodyLengthRead > contentLen {
@@ -86,13 +86,32 @@ func TestRequestLineParse(t *testing.T) {
 	_, err = RequestFromReader(reader)
 	require.Error(t, err)
 
-	// Test: Invalid HTTP version in request line
+	// Test: Good HTTP/1.0 request line
 	reader = &chunkReader{
-		data:            "GET /coffee HTTP/1.0\r\nHost: localhost:42069\r\nUser-Agent: curl/7.81.0\r\nAccept: */*\r\n\r\n",
+		data:            "GET /coffee HTTP/1.0\r\nUser-Agent: curl/7.81.0\r\nAccept: */*\r\n\r\n",
+		numBytesPerRead: 8,
+	}
+	r, err = RequestFromReader(reader)
+	require.NoError(t, err)
+	require.NotNil(t, r)
+	assert.Equal(t, "1.0", r.RequestLine.HttpVersion)
+
+	// Test: Unsupported HTTP version in request line
+	reader = &chunkReader{
+		data:            "GET /coffee HTTP/2.0\r\nHost: localhost:42069\r\nUser-Agent: curl/7.81.0\r\nAccept: */*\r\n\r\n",
+		numBytesPerRead: 8,
+	}
+	_, err = RequestFromReader(reader)
+	require.ErrorIs(t, err, ErrUnsupportedVersion)
+
+	// Test: Malformed HTTP version in request line
+	reader = &chunkReader{
+		data:            "GET /coffee HTTP/one\r\nHost: localhost:42069\r\n\r\n",
 		numBytesPerRead: 8,
 	}
 	_, err = RequestFromReader(reader)
 	require.Error(t, err)
+	assert.NotErrorIs(t, err, ErrUnsupportedVersion)
 }
 
 func TestHeadersParsing(t *testing.T) {
@@ -122,9 +141,9 @@ func TestHeadersParsing(t *testing.T) {
 	r, err = RequestFromReader(reader)
 	require.Error(t, err)
 
-	// Test: Empty Headers
+	// Test: Empty Headers (HTTP/1.0, since HTTP/1.1 requires Host)
 	reader = &chunkReader{
-		data:            "GET / HTTP/1.1\r\n\r\n",
+		data:            "GET / HTTP/1.0\r\n\r\n",
 		numBytesPerRead: 1,
 	}
 	r, err = RequestFromReader(reader)
@@ -132,14 +151,14 @@ func TestHeadersParsing(t *testing.T) {
 	require.NotNil(t, r)
 	assert.Equal(t, "GET", r.RequestLine.Method)
 	assert.Equal(t, "/", r.RequestLine.RequestTarget)
-	assert.Equal(t, "1.1", r.RequestLine.HttpVersion)
+	assert.Equal(t, "1.0", r.RequestLine.HttpVersion)
 
 	headers = r.Headers
 	require.NotNil(t, headers)
 
 	// Test: Duplicate Headers
 	reader = &chunkReader{
-		data:            "GET / HTTP/1.1\r\nHost: localhost:42069\r\nHost: localhost:42069\r\n\r\n",
+		data:            "GET / HTTP/1.1\r\nHost: localhost:42069\r\nAccept: text/html\r\nAccept: */*\r\n\r\n",
 		numBytesPerRead: 4,
 	}
 	r, err = RequestFromReader(reader)
@@ -151,7 +170,7 @@ func TestHeadersParsing(t *testing.T) {
 
 	headers = r.Headers
 	require.NotNil(t, headers)
-	assert.Equal(t, "localhost:42069, localhost:42069", headers["host"])
+	assert.Equal(t, "text/html, */*", headers["accept"])
 
 	// Test: Case Insensitive Headers
 	reader = &chunkReader{
@@ -246,3 +265,77 @@ func TestBodyParsing(t *testing.T) {
 	require.NotNil(t, r)
 	assert.Equal(t, "", string(r.Body))
 }
+
+func TestDeferredBodyReading(t *testing.T) {
+	// Test: Headers only, body read on demand after the hook fires
+	reader := &chunkReader{
+		data: "POST /upload HTTP/1.1\r\n" +
+			"Host: localhost:42069\r\n" +
+			"Expect: 100-continue\r\n" +
+			"Content-Length: 13\r\n" +
+			"\r\n" +
+			"hello world!\n",
+		numBytesPerRead: 3,
+	}
+	r, err := HeadersFromReader(reader)
+	require.NoError(t, err)
+	require.NotNil(t, r)
+	assert.True(t, r.ExpectsContinue())
+	assert.Equal(t, "", string(r.Body))
+	hookCalls := 0
+	r.BeforeBodyRead(func() error {
+		hookCalls++
+		return nil
+	})
+	body, err := r.ReadBody()
+	require.NoError(t, err)
+	assert.Equal(t, "hello world!\n", string(body))
+	assert.Equal(t, 1, hookCalls)
+	body, err = r.ReadBody()
+	require.NoError(t, err)
+	assert.Equal(t, "hello world!\n", string(body))
+	assert.Equal(t, 1, hookCalls)
+
+	// Test: No body means the hook never fires
+	reader = &chunkReader{
+		data:            "GET / HTTP/1.1\r\nHost: localhost:42069\r\nExpect: 100-continue\r\n\r\n",
+		numBytesPerRead: 3,
+	}
+	r, err = HeadersFromReader(reader)
+	require.NoError(t, err)
+	r.BeforeBodyRead(func() error {
+		hookCalls++
+		return nil
+	})
+	_, err = r.ReadBody()
+	require.NoError(t, err)
+	assert.Equal(t, 1, hookCalls)
+
+	// Test: Body that arrived with the headers skips the hook
+	reader = &chunkReader{
+		data:            "POST / HTTP/1.1\r\nHost: localhost:42069\r\nContent-Length: 5\r\n\r\nhello",
+		numBytesPerRead: 100,
+	}
+	r, err = HeadersFromReader(reader)
+	require.NoError(t, err)
+	assert.False(t, r.ExpectsContinue())
+	r.BeforeBodyRead(func() error {
+		return io.ErrClosedPipe
+	})
+	body, err = r.ReadBody()
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(body))
+
+	// Test: Hook error aborts the body read
+	reader = &chunkReader{
+		data:            "POST / HTTP/1.1\r\nHost: localhost:42069\r\nContent-Length: 5\r\n\r\nhello",
+		numBytesPerRead: 1,
+	}
+	r, err = HeadersFromReader(reader)
+	require.NoError(t, err)
+	r.BeforeBodyRead(func() error {
+		return io.ErrClosedPipe
+	})
+	_, err = r.ReadBody()
+	require.ErrorIs(t, err, io.ErrClosedPipe)
+}
@@ -0,0 +1,135 @@
+package request
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// TargetForm is one of the four request-target forms from RFC 9112 section 3.2.
+type TargetForm int
+
+const (
+	TargetOriginForm TargetForm = iota
+	TargetAbsoluteForm
+	TargetAuthorityForm
+	TargetAsteriskForm
+)
+
+var ErrInvalidTarget = errors.New("invalid request-target")
+
+// Target is the parsed request-target. Path is percent-decoded, RawPath is
+// the path exactly as received. Scheme and Host are only set for the
+// absolute and authority forms.
+type Target struct {
+	Form     TargetForm
+	Scheme   string
+	Host     string
+	Path     string
+	RawPath  string
+	RawQuery string
+	Query    url.Values
+	Fragment string
+}
+
+// ParseTarget classifies and decodes a raw request-target. The method decides
+// which forms are allowed: authority-form only for CONNECT and asterisk-form
+// only for OPTIONS.
+func ParseTarget(method, raw string) (Target, error) {
+	for i := 0; i < len(raw); i++ {
+		if raw[i] <= ' ' || raw[i] == 0x7f {
+			return Target{}, fmt.Errorf("%w: control character in %q", ErrInvalidTarget, raw)
+		}
+	}
+
+	switch {
+	case raw == "":
+		return Target{}, fmt.Errorf("%w: empty", ErrInvalidTarget)
+	case method == "CONNECT":
+		return parseAuthorityForm(raw)
+	case raw == "*":
+		if method != "OPTIONS" {
+			return Target{}, fmt.Errorf("%w: asterisk-form is only allowed for OPTIONS", ErrInvalidTarget)
+		}
+		return Target{Form: TargetAsteriskForm, Query: url.Values{}}, nil
+	case strings.HasPrefix(raw, "/"):
+		t := Target{Form: TargetOriginForm}
+		err := t.parsePathAndQuery(raw)
+		return t, err
+	default:
+		return parseAbsoluteForm(raw)
+	}
+}
+
+func parseAuthorityForm(raw string) (Target, error) {
+	if strings.ContainsAny(raw, "/?#@") {
+		return Target{}, fmt.Errorf("%w: authority-form must be host:port, got %q", ErrInvalidTarget, raw)
+	}
+	idx := strings.LastIndex(raw, ":")
+	if idx <= 0 || idx == len(raw)-1 {
+		return Target{}, fmt.Errorf("%w: authority-form must include a port, got %q", ErrInvalidTarget, raw)
+	}
+	return Target{Form: TargetAuthorityForm, Host: raw, Query: url.Values{}}, nil
+}
+
+func parseAbsoluteForm(raw string) (Target, error) {
+	scheme, rest, ok := strings.Cut(raw, "://")
+	if !ok || !validScheme(scheme) {
+		return Target{}, fmt.Errorf("%w: %q", ErrInvalidTarget, raw)
+	}
+	end := strings.IndexAny(rest, "/?#")
+	if end == -1 {
+		end = len(rest)
+	}
+	t := Target{
+		Form:   TargetAbsoluteForm,
+		Scheme: strings.ToLower(scheme),
+		Host:   rest[:end],
+	}
+	if t.Host == "" || strings.Contains(t.Host, "@") {
+		return Target{}, fmt.Errorf("%w: bad authority in %q", ErrInvalidTarget, raw)
+	}
+	pathAndQuery := rest[end:]
+	if !strings.HasPrefix(pathAndQuery, "/") {
+		pathAndQuery = "/" + pathAndQuery
+	}
+	err := t.parsePathAndQuery(pathAndQuery)
+	return t, err
+}
+
+func (t *Target) parsePathAndQuery(raw string) error {
+	raw, t.Fragment, _ = strings.Cut(raw, "#")
+	t.RawPath, t.RawQuery, _ = strings.Cut(raw, "?")
+
+	path, err := url.PathUnescape(t.RawPath)
+	if err != nil {
+		return fmt.Errorf("%w: bad percent-encoding in path %q", ErrInvalidTarget, t.RawPath)
+	}
+	t.Path = path
+
+	_, err = url.QueryUnescape(t.RawQuery)
+	if err != nil {
+		return fmt.Errorf("%w: bad percent-encoding in query %q", ErrInvalidTarget, t.RawQuery)
+	}
+	// ParseQuery also fails on semicolons, but only drops those pairs;
+	// that is no reason to reject the request
+	t.Query, _ = url.ParseQuery(t.RawQuery)
+	return nil
+}
+
+func validScheme(scheme string) bool {
+	if scheme == "" {
+		return false
+	}
+	for i := 0; i < len(scheme); i++ {
+		c := scheme[i]
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+		case i > 0 && (isDigit(c) || c == '+' || c == '-' || c == '.'):
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,93 @@
+package request
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTarget(t *testing.T) {
+	// Test: Origin-form with query and fragment
+	target, err := ParseTarget("GET", "/video%20clips/a%2Fb?x=1&tag=a&tag=b#t=10")
+	require.NoError(t, err)
+	assert.Equal(t, TargetOriginForm, target.Form)
+	assert.Equal(t, "/video clips/a/b", target.Path)
+	assert.Equal(t, "/video%20clips/a%2Fb", target.RawPath)
+	assert.Equal(t, "x=1&tag=a&tag=b", target.RawQuery)
+	assert.Equal(t, "1", target.Query.Get("x"))
+	assert.Equal(t, []string{"a", "b"}, target.Query["tag"])
+	assert.Equal(t, "t=10", target.Fragment)
+
+	// Test: Absolute-form
+	target, err = ParseTarget("GET", "HTTP://example.com:8080/coffee?size=large")
+	require.NoError(t, err)
+	assert.Equal(t, TargetAbsoluteForm, target.Form)
+	assert.Equal(t, "http", target.Scheme)
+	assert.Equal(t, "example.com:8080", target.Host)
+	assert.Equal(t, "/coffee", target.Path)
+	assert.Equal(t, "large", target.Query.Get("size"))
+
+	// Test: Absolute-form without a path
+	target, err = ParseTarget("GET", "http://example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "/", target.Path)
+
+	// Test: Authority-form for CONNECT
+	target, err = ParseTarget("CONNECT", "example.com:443")
+	require.NoError(t, err)
+	assert.Equal(t, TargetAuthorityForm, target.Form)
+	assert.Equal(t, "example.com:443", target.Host)
+
+	// Test: Authority-form without a port
+	_, err = ParseTarget("CONNECT", "example.com")
+	require.ErrorIs(t, err, ErrInvalidTarget)
+
+	// Test: Asterisk-form for OPTIONS
+	target, err = ParseTarget("OPTIONS", "*")
+	require.NoError(t, err)
+	assert.Equal(t, TargetAsteriskForm, target.Form)
+
+	// Test: Asterisk-form for other methods
+	_, err = ParseTarget("GET", "*")
+	require.ErrorIs(t, err, ErrInvalidTarget)
+
+	// Test: Invalid percent-encoding in path
+	_, err = ParseTarget("GET", "/bad%zzpath")
+	require.ErrorIs(t, err, ErrInvalidTarget)
+
+	// Test: Semicolons in the query are not an error
+	target, err = ParseTarget("GET", "/search?a=1;b=2&c=3")
+	require.NoError(t, err)
+	assert.Equal(t, "a=1;b=2&c=3", target.RawQuery)
+	assert.Equal(t, "3", target.Query.Get("c"))
+
+	// Test: Invalid percent-encoding in query
+	_, err = ParseTarget("GET", "/ok?q=%")
+	require.ErrorIs(t, err, ErrInvalidTarget)
+
+	// Test: Neither a path nor an absolute URI
+	_, err = ParseTarget("GET", "coffee")
+	require.ErrorIs(t, err, ErrInvalidTarget)
+}
+
+func TestRequestTargetParsing(t *testing.T) {
+	// Test: Target is parsed along with the request-line
+	reader := &chunkReader{
+		data:            "GET /video?x=1 HTTP/1.1\r\nHost: localhost:42069\r\n\r\n",
+		numBytesPerRead: 3,
+	}
+	r, err := RequestFromReader(reader)
+	require.NoError(t, err)
+	assert.Equal(t, "/video?x=1", r.RequestLine.RequestTarget)
+	assert.Equal(t, "/video", r.Target.Path)
+	assert.Equal(t, "1", r.Target.Query.Get("x"))
+
+	// Test: Bad percent-encoding fails the request
+	reader = &chunkReader{
+		data:            "GET /%G0 HTTP/1.1\r\nHost: localhost:42069\r\n\r\n",
+		numBytesPerRead: 3,
+	}
+	_, err = RequestFromReader(reader)
+	require.ErrorIs(t, err, ErrInvalidTarget)
+}
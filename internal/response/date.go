@@ -0,0 +1,32 @@
+package response
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/UUest/httpfromtcp/internal/headers"
+)
+
+// now is replaced in tests.
+var now = time.Now
+
+type cachedDate struct {
+	unix  int64
+	value string
+}
+
+// dateCache holds the formatted Date of the current second, so busy servers
+// do not format the same timestamp for every response.
+var dateCache atomic.Pointer[cachedDate]
+
+// currentDate returns the current time in IMF-fixdate.
+func currentDate() string {
+	t := now()
+	unix := t.Unix()
+	if c := dateCache.Load(); c != nil && c.unix == unix {
+		return c.value
+	}
+	value := headers.FormatTime(t)
+	dateCache.Store(&cachedDate{unix: unix, value: value})
+	return value
+}
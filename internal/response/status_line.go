@@ -7,20 +7,80 @@ import (
 type StatusCode int
 
 const (
-	StatusCodeSuccess             StatusCode = 200
-	StatusCodeBadRequest          StatusCode = 400
-	StatusCodeInternalServerError StatusCode = 500
+	StatusCodeContinue             StatusCode = 100
+	StatusCodeSwitchingProtocols   StatusCode = 101
+	StatusCodeEarlyHints           StatusCode = 103
+	StatusCodeSuccess              StatusCode = 200
+	StatusCodePartialContent       StatusCode = 206
+	StatusCodeNotModified          StatusCode = 304
+	StatusCodePermanentRedirect    StatusCode = 308
+	StatusCodeBadRequest           StatusCode = 400
+	StatusCodeForbidden            StatusCode = 403
+	StatusCodeNotFound             StatusCode = 404
+	StatusCodeMethodNotAllowed     StatusCode = 405
+	StatusCodeNotAcceptable        StatusCode = 406
+	StatusCodePreconditionFailed   StatusCode = 412
+	StatusCodeContentTooLarge      StatusCode = 413
+	StatusCodeUnsupportedMediaType StatusCode = 415
+	StatusCodeRangeNotSatisfiable  StatusCode = 416
+	StatusCodeExpectationFailed    StatusCode = 417
+	StatusCodeUpgradeRequired      StatusCode = 426
+	StatusCodeInternalServerError  StatusCode = 500
+	StatusCodeNotImplemented       StatusCode = 501
+	StatusCodeVersionNotSupported  StatusCode = 505
 )
 
 func getStatusLine(statusCode StatusCode) []byte {
+	return []byte(fmt.Sprintf("HTTP/1.1 %d %s\r\n", statusCode, StatusText(statusCode)))
+}
+
+// StatusText returns the reason phrase for statusCode, or "" if it is not one
+// of the codes above.
+func StatusText(statusCode StatusCode) string {
 	reasonPhrase := ""
 	switch statusCode {
+	case StatusCodeContinue:
+		reasonPhrase = "Continue"
+	case StatusCodeSwitchingProtocols:
+		reasonPhrase = "Switching Protocols"
+	case StatusCodeEarlyHints:
+		reasonPhrase = "Early Hints"
 	case StatusCodeSuccess:
 		reasonPhrase = "OK"
+	case StatusCodePartialContent:
+		reasonPhrase = "Partial Content"
+	case StatusCodeNotModified:
+		reasonPhrase = "Not Modified"
+	case StatusCodePermanentRedirect:
+		reasonPhrase = "Permanent Redirect"
 	case StatusCodeBadRequest:
 		reasonPhrase = "Bad Request"
+	case StatusCodeForbidden:
+		reasonPhrase = "Forbidden"
+	case StatusCodeNotFound:
+		reasonPhrase = "Not Found"
+	case StatusCodeMethodNotAllowed:
+		reasonPhrase = "Method Not Allowed"
+	case StatusCodeNotAcceptable:
+		reasonPhrase = "Not Acceptable"
+	case StatusCodePreconditionFailed:
+		reasonPhrase = "Precondition Failed"
+	case StatusCodeContentTooLarge:
+		reasonPhrase = "Content Too Large"
+	case StatusCodeUnsupportedMediaType:
+		reasonPhrase = "Unsupported Media Type"
+	case StatusCodeRangeNotSatisfiable:
+		reasonPhrase = "Range Not Satisfiable"
+	case StatusCodeExpectationFailed:
+		reasonPhrase = "Expectation Failed"
+	case StatusCodeUpgradeRequired:
+		reasonPhrase = "Upgrade Required"
 	case StatusCodeInternalServerError:
 		reasonPhrase = "Internal Server Error"
+	case StatusCodeNotImplemented:
+		reasonPhrase = "Not Implemented"
+	case StatusCodeVersionNotSupported:
+		reasonPhrase = "HTTP Version Not Supported"
 	}
-	return []byte(fmt.Sprintf("HTTP/1.1 %d %s\r\n", statusCode, reasonPhrase))
+	return reasonPhrase
 }
@@ -1,9 +1,12 @@
 package response
 
 import (
+	"errors"
 	"fmt"
 	"io"
+	"net"
 
+	"github.com/UUest/httpfromtcp/internal/cookie"
 	"github.com/UUest/httpfromtcp/internal/headers"
 )
 
@@ -14,41 +17,206 @@ const (
 	writerStateHeaders
 	writerStateBody
 	writerStateTrailers
+	writerStateHijacked
 )
 
 type Writer struct {
 	writerState writerState
 	writer      io.Writer
+	http10      bool
+	sanitize    bool
+	setCookies  []string
+	statusCode  StatusCode
+	encodeBody  BodyEncoder
+	bodyEncoder io.WriteCloser
+	noBody      bool
+	sendDate    bool
+	server      string
 }
 
+// BodyEncoder is called by WriteHeaders with the final status code and a copy
+// of the headers, which it may change. It returns a function that wraps the
+// body writer, e.g. with a compressor, or nil to send the body unchanged.
+// Encoded bodies are sent chunked since their length is not known upfront.
+// If the wrapping writer has a Flush() error method, it is flushed after every
+// WriteChunkedBody call.
+type BodyEncoder func(statusCode StatusCode, h headers.Headers) func(io.Writer) io.WriteCloser
+
 func NewWriter(w io.Writer) *Writer {
 	return &Writer{
 		writerState: writerStateStatusLine,
 		writer:      w,
+		sendDate:    true,
 	}
 }
 
+// SetRequestVersion tells the Writer which HTTP-version the client used.
+// HTTP/1.0 clients get no interim responses and no chunked framing: chunked
+// bodies are sent as-is and delimited by closing the connection.
+func (w *Writer) SetRequestVersion(version string) {
+	w.http10 = version == "1.0"
+}
+
+// SuppressBody makes the Writer drop everything after the header section
+// while still reporting success, as needed for responses to HEAD. Headers,
+// including Content-Length, are sent as the handler wrote them.
+func (w *Writer) SuppressBody(suppress bool) {
+	w.noBody = suppress
+}
+
 func (w *Writer) WriteStatusLine(statusCode StatusCode) error {
 	if w.writerState != writerStateStatusLine {
 		return fmt.Errorf("cannot write status line in state %d", w.writerState)
 	}
 	defer func() { w.writerState = writerStateHeaders }()
+	w.statusCode = statusCode
 	_, err := w.writer.Write(getStatusLine(statusCode))
 	return err
 }
 
+// WriteInformational sends an interim 1xx response with its own headers, such
+// as 103 Early Hints. Any number of them may precede the final status line.
+// 101 Switching Protocols is final for this connection and must be sent with
+// WriteStatusLine instead.
+func (w *Writer) WriteInformational(statusCode StatusCode, h headers.Headers) error {
+	if w.writerState != writerStateStatusLine {
+		return fmt.Errorf("cannot write informational response in state %d", w.writerState)
+	}
+	if statusCode < 100 || statusCode > 199 || statusCode == StatusCodeSwitchingProtocols {
+		return fmt.Errorf("invalid informational status code: %d", statusCode)
+	}
+	if w.http10 {
+		return nil
+	}
+	h, err := w.checkFields(h)
+	if err != nil {
+		return err
+	}
+	_, err = w.writer.Write(getStatusLine(statusCode))
+	if err != nil {
+		return err
+	}
+	return w.writeFieldLines(h, nil)
+}
+
+// WriteContinue sends an interim 100 Continue response. It does nothing once
+// the final status line has been written, since the client no longer needs
+// permission to send the body.
+func (w *Writer) WriteContinue() error {
+	if w.writerState != writerStateStatusLine {
+		return nil
+	}
+	return w.WriteInformational(StatusCodeContinue, nil)
+}
+
 func (w *Writer) WriteHeaders(h headers.Headers) error {
 	if w.writerState != writerStateHeaders {
 		return fmt.Errorf("cannot write headers in state %d", w.writerState)
 	}
-	defer func() { w.writerState = writerStateBody }()
-	for k, v := range h {
-		_, err := w.writer.Write([]byte(fmt.Sprintf("%s: %s\r\n", k, v)))
-		if err != nil {
-			return err
+	h = w.addAutomaticHeaders(h)
+	var wrap func(io.Writer) io.WriteCloser
+	if w.encodeBody != nil {
+		h = copyHeaders(h)
+		wrap = w.encodeBody(w.statusCode, h)
+		if wrap != nil {
+			h.Remove("Content-Length")
+			h.Override("Transfer-Encoding", "chunked")
 		}
 	}
-	_, err := w.writer.Write([]byte("\r\n"))
+	h, err := w.checkFields(h)
+	if err != nil {
+		return err
+	}
+	defer func() { w.writerState = writerStateBody }()
+	if _, ok := h.Get("Transfer-Encoding"); ok && w.http10 {
+		h = copyHeaders(h)
+		h.Remove("Transfer-Encoding")
+		h.Remove("Trailer")
+		h.Override("Connection", "close")
+	}
+	err = w.writeFieldLines(h, w.setCookies)
+	if err != nil {
+		return err
+	}
+	if wrap != nil && !w.noBody {
+		w.bodyEncoder = wrap(chunkWriter{w})
+	}
+	return nil
+}
+
+// SetDateHeader controls whether WriteHeaders adds a Date header with the
+// current time. It is on by default; a Date set by the handler is always kept.
+func (w *Writer) SetDateHeader(enabled bool) {
+	w.sendDate = enabled
+}
+
+// SetServerHeader makes WriteHeaders add a Server header with the given
+// value, unless the handler set one. An empty value sends none.
+func (w *Writer) SetServerHeader(value string) {
+	w.server = value
+}
+
+// addAutomaticHeaders returns h with Date and Server added where enabled and
+// missing. h itself is not modified.
+func (w *Writer) addAutomaticHeaders(h headers.Headers) headers.Headers {
+	_, hasDate := h.Get("Date")
+	_, hasServer := h.Get("Server")
+	addDate := w.sendDate && !hasDate
+	addServer := w.server != "" && !hasServer
+	if !addDate && !addServer {
+		return h
+	}
+	h = copyHeaders(h)
+	if addDate {
+		h.Set("Date", currentDate())
+	}
+	if addServer {
+		h.Set("Server", w.server)
+	}
+	return h
+}
+
+// SetBodyEncoder installs enc to be consulted by WriteHeaders. It is meant
+// for middleware such as response compression and must be called before
+// WriteHeaders.
+func (w *Writer) SetBodyEncoder(enc BodyEncoder) {
+	w.encodeBody = enc
+}
+
+// chunkWriter frames everything written to it as chunks, so a BodyEncoder
+// can flush whenever it likes.
+type chunkWriter struct {
+	w *Writer
+}
+
+func (cw chunkWriter) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	_, err := cw.w.writeChunk(p)
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// finishEncoded flushes the body encoder and ends the chunked body. If
+// withTrailers is false the empty trailer section is written as well.
+func (w *Writer) finishEncoded(withTrailers bool) error {
+	enc := w.bodyEncoder
+	w.bodyEncoder = nil
+	err := enc.Close()
+	if err != nil {
+		return err
+	}
+	if w.http10 {
+		return nil
+	}
+	end := "0\r\n"
+	if !withTrailers {
+		end += "\r\n"
+	}
+	_, err = w.writer.Write([]byte(end))
 	return err
 }
 
@@ -57,13 +225,67 @@ func (w *Writer) WriteBody(p []byte) (int, error) {
 		return 0, fmt.Errorf("cannot write body in state %d", w.writerState)
 	}
 	defer func() { w.writerState = writerStateTrailers }()
+	if w.noBody {
+		return len(p), nil
+	}
+	if w.bodyEncoder != nil {
+		n, err := w.bodyEncoder.Write(p)
+		if err != nil {
+			return n, err
+		}
+		return n, w.finishEncoded(false)
+	}
 	return w.writer.Write(p)
 }
 
+// WriteBodyFrom copies the whole body from r, so large bodies such as files
+// can be streamed instead of being loaded into memory. Like WriteBody it
+// writes the entire body in one call. io.Copy hands r to the connection's
+// ReadFrom, so on a TCP connection a file body is sent with sendfile.
+func (w *Writer) WriteBodyFrom(r io.Reader) (int64, error) {
+	if w.writerState != writerStateBody {
+		return 0, fmt.Errorf("cannot write body in state %d", w.writerState)
+	}
+	defer func() { w.writerState = writerStateTrailers }()
+	if w.noBody {
+		// nothing would be sent, so the source is not read at all
+		return 0, nil
+	}
+	if w.bodyEncoder != nil {
+		n, err := io.Copy(w.bodyEncoder, r)
+		if err != nil {
+			return n, err
+		}
+		return n, w.finishEncoded(false)
+	}
+	return io.Copy(w.writer, r)
+}
+
 func (w *Writer) WriteChunkedBody(p []byte) (int, error) {
 	if w.writerState != writerStateBody {
 		return 0, fmt.Errorf("cannot write body in state %d", w.writerState)
 	}
+	if w.noBody {
+		return len(p), nil
+	}
+	if w.bodyEncoder != nil {
+		n, err := w.bodyEncoder.Write(p)
+		if err != nil {
+			return n, err
+		}
+		// streamed bodies such as event streams must not sit in the encoder
+		if f, ok := w.bodyEncoder.(interface{ Flush() error }); ok {
+			return n, f.Flush()
+		}
+		return n, nil
+	}
+	return w.writeChunk(p)
+}
+
+func (w *Writer) writeChunk(p []byte) (int, error) {
+	if w.http10 {
+		return w.writer.Write(p)
+	}
 	chunkSize := len(p)
 
 	nTotal := 0
@@ -92,6 +314,15 @@ func (w *Writer) WriteChunkedBodyDone() (int, error) {
 		return 0, fmt.Errorf("cannot write body in state %d", w.writerState)
 	}
 	defer func() { w.writerState = writerStateTrailers }()
+	if w.noBody {
+		return 0, nil
+	}
+	if w.bodyEncoder != nil {
+		return 0, w.finishEncoded(true)
+	}
+	if w.http10 {
+		return 0, nil
+	}
 	n, err := w.writer.Write([]byte("0\r\n"))
 	if err != nil {
 		return n, err
@@ -103,12 +334,122 @@ func (w *Writer) WriteTrailers(h headers.Headers) error {
 	if w.writerState != writerStateTrailers {
 		return fmt.Errorf("cannot write trailers in state %d", w.writerState)
 	}
+	if w.http10 || w.noBody {
+		// there is no way to send trailers without chunked framing, and a
+		// response without a body has no trailer section
+		return nil
+	}
+	h, err := w.checkFields(h)
+	if err != nil {
+		return err
+	}
+	return w.writeFieldLines(h, nil)
+}
+
+// SanitizeHeaders makes the Writer replace forbidden characters in header
+// values (CR, LF, NUL and other controls) with spaces instead of failing.
+// Invalid header names are always an error.
+func (w *Writer) SanitizeHeaders(enable bool) {
+	w.sanitize = enable
+}
+
+// checkFields validates h before anything is written, so a handler that
+// copies untrusted input into a header cannot inject extra fields or split
+// the response. It returns the fields to write, which differ from h only when
+// values had to be sanitized.
+func (w *Writer) checkFields(h headers.Headers) (headers.Headers, error) {
+	var sanitized headers.Headers
+	for k, v := range h {
+		if !headers.ValidFieldName(k) {
+			return nil, fmt.Errorf("%w: %q", headers.ErrInvalidFieldName, k)
+		}
+		if headers.ValidFieldValue(v) {
+			continue
+		}
+		if !w.sanitize {
+			return nil, fmt.Errorf("%w for %s: %q", headers.ErrInvalidFieldValue, k, v)
+		}
+		if sanitized == nil {
+			sanitized = copyHeaders(h)
+		}
+		sanitized.Override(k, sanitizeFieldValue(v))
+	}
+	if sanitized != nil {
+		return sanitized, nil
+	}
+	return h, nil
+}
+
+func sanitizeFieldValue(v string) string {
+	b := []byte(v)
+	for i, c := range b {
+		if (c < ' ' && c != '\t') || c == 0x7f {
+			b[i] = ' '
+		}
+	}
+	return string(b)
+}
+
+// AddCookie queues a Set-Cookie header for the response. Each cookie gets its
+// own field line; Set-Cookie must never be folded into a comma-separated list
+// with headers.Set, because Expires contains a comma. It must be called before
+// WriteHeaders.
+func (w *Writer) AddCookie(c *cookie.Cookie) error {
+	if w.writerState != writerStateStatusLine && w.writerState != writerStateHeaders {
+		return fmt.Errorf("cannot add cookie in state %d", w.writerState)
+	}
+	v, err := c.Format()
+	if err != nil {
+		return err
+	}
+	w.setCookies = append(w.setCookies, v)
+	return nil
+}
+
+// writeFieldLines writes h and one set-cookie line per entry in setCookies,
+// followed by the empty line that ends a header or trailer section.
+func (w *Writer) writeFieldLines(h headers.Headers, setCookies []string) error {
 	for k, v := range h {
 		_, err := w.writer.Write([]byte(fmt.Sprintf("%s: %s\r\n", k, v)))
 		if err != nil {
 			return err
 		}
 	}
-	_, err := w.writer.Write([]byte("\r\n\r\n"))
+	for _, v := range setCookies {
+		_, err := w.writer.Write([]byte(fmt.Sprintf("set-cookie: %s\r\n", v)))
+		if err != nil {
+			return err
+		}
+	}
+	_, err := w.writer.Write([]byte("\r\n"))
 	return err
 }
+
+// Hijack hands the underlying connection over to the caller, e.g. after a
+// 101 Switching Protocols response. The Writer must not be used afterwards.
+// The server leaves a hijacked connection open when the handler returns, so
+// the caller is responsible for closing it.
+func (w *Writer) Hijack() (net.Conn, error) {
+	if w.writerState == writerStateHijacked {
+		return nil, errors.New("connection already hijacked")
+	}
+	conn, ok := w.writer.(net.Conn)
+	if !ok {
+		return nil, errors.New("underlying writer is not a connection")
+	}
+	w.writerState = writerStateHijacked
+	return conn, nil
+}
+
+// Hijacked reports whether Hijack has handed the connection to the caller.
+func (w *Writer) Hijacked() bool {
+	return w.writerState == writerStateHijacked
+}
+
+func copyHeaders(h headers.Headers) headers.Headers {
+	c := headers.NewHeaders()
+	for k, v := range h {
+		c.Override(k, v)
+	}
+	return c
+}
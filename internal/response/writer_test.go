@@ -0,0 +1,323 @@
+package response
+
+import (
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/UUest/httpfromtcp/internal/cookie"
+	"github.com/UUest/httpfromtcp/internal/headers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteInformational(t *testing.T) {
+	// Test: Early hints and 100 Continue before the final response
+	var out strings.Builder
+	w := newTestWriter(&out)
+	hints := headers.NewHeaders()
+	hints.Set("Link", "</style.css>; rel=preload; as=style")
+	require.NoError(t, w.WriteInformational(StatusCodeEarlyHints, hints))
+	require.NoError(t, w.WriteContinue())
+	require.NoError(t, w.WriteStatusLine(StatusCodeSuccess))
+	require.NoError(t, w.WriteHeaders(headers.NewHeaders()))
+	assert.Equal(t, "HTTP/1.1 103 Early Hints\r\n"+
+		"link: </style.css>; rel=preload; as=style\r\n"+
+		"\r\n"+
+		"HTTP/1.1 100 Continue\r\n"+
+		"\r\n"+
+		"HTTP/1.1 200 OK\r\n"+
+		"\r\n", out.String())
+
+	// Test: Informational responses are rejected after the final status line
+	require.Error(t, w.WriteInformational(StatusCodeEarlyHints, nil))
+	require.NoError(t, w.WriteContinue())
+
+	// Test: Non-1xx and 101 codes are rejected
+	w = newTestWriter(&out)
+	require.Error(t, w.WriteInformational(StatusCodeSuccess, nil))
+	require.Error(t, w.WriteInformational(StatusCodeSwitchingProtocols, nil))
+	require.NoError(t, w.WriteStatusLine(StatusCodeSuccess))
+}
+
+func TestHTTP10Framing(t *testing.T) {
+	// Test: Chunked response to an HTTP/1.0 client is close-delimited
+	var out strings.Builder
+	w := newTestWriter(&out)
+	w.SetRequestVersion("1.0")
+	require.NoError(t, w.WriteInformational(StatusCodeEarlyHints, nil))
+	require.NoError(t, w.WriteStatusLine(StatusCodeSuccess))
+	h := headers.NewHeaders()
+	h.Set("Transfer-Encoding", "chunked")
+	h.Set("Trailer", "X-Checksum")
+	require.NoError(t, w.WriteHeaders(h))
+	_, err := w.WriteChunkedBody([]byte("hello "))
+	require.NoError(t, err)
+	_, err = w.WriteChunkedBody([]byte("world"))
+	require.NoError(t, err)
+	_, err = w.WriteChunkedBodyDone()
+	require.NoError(t, err)
+	trailers := headers.NewHeaders()
+	trailers.Set("X-Checksum", "abc")
+	require.NoError(t, w.WriteTrailers(trailers))
+	assert.Equal(t, "HTTP/1.1 200 OK\r\nconnection: close\r\n\r\nhello world", out.String())
+	_, stillChunked := h.Get("Transfer-Encoding")
+	assert.True(t, stillChunked)
+}
+
+func TestTrailerTerminator(t *testing.T) {
+	// Test: An empty trailer section ends the message with a single CRLF
+	var out strings.Builder
+	w := newTestWriter(&out)
+	require.NoError(t, w.WriteStatusLine(StatusCodeSuccess))
+	h := headers.NewHeaders()
+	h.Set("Transfer-Encoding", "chunked")
+	require.NoError(t, w.WriteHeaders(h))
+	_, err := w.WriteChunkedBody([]byte("hi"))
+	require.NoError(t, err)
+	_, err = w.WriteChunkedBodyDone()
+	require.NoError(t, err)
+	require.NoError(t, w.WriteTrailers(nil))
+	assert.True(t, strings.HasSuffix(out.String(), "\r\n\r\n2\r\nhi\r\n0\r\n\r\n"))
+
+	// Test: Trailer fields are followed by a single CRLF
+	out.Reset()
+	w = newTestWriter(&out)
+	require.NoError(t, w.WriteStatusLine(StatusCodeSuccess))
+	require.NoError(t, w.WriteHeaders(h))
+	_, err = w.WriteChunkedBodyDone()
+	require.NoError(t, err)
+	trailers := headers.NewHeaders()
+	trailers.Set("X-Checksum", "abc")
+	require.NoError(t, w.WriteTrailers(trailers))
+	assert.True(t, strings.HasSuffix(out.String(), "\r\n\r\n0\r\nx-checksum: abc\r\n\r\n"))
+}
+
+func TestHeaderInjection(t *testing.T) {
+	// Test: CRLF in a value is rejected and nothing is written
+	var out strings.Builder
+	w := newTestWriter(&out)
+	require.NoError(t, w.WriteStatusLine(StatusCodeSuccess))
+	before := out.Len()
+	h := headers.NewHeaders()
+	h.Set("X-Upstream", "ok\r\nSet-Cookie: session=evil")
+	err := w.WriteHeaders(h)
+	require.ErrorIs(t, err, headers.ErrInvalidFieldValue)
+	assert.Equal(t, before, out.Len())
+
+	// Test: Headers can be written once the value is fixed
+	h.Override("X-Upstream", "ok")
+	require.NoError(t, w.WriteHeaders(h))
+
+	// Test: Invalid header name
+	w = newTestWriter(&out)
+	require.NoError(t, w.WriteStatusLine(StatusCodeSuccess))
+	h = headers.NewHeaders()
+	h["X-Bad\r\nName"] = "v"
+	require.ErrorIs(t, w.WriteHeaders(h), headers.ErrInvalidFieldName)
+
+	// Test: NUL in a trailer
+	w = newTestWriter(&out)
+	require.NoError(t, w.WriteStatusLine(StatusCodeSuccess))
+	require.NoError(t, w.WriteHeaders(headers.NewHeaders()))
+	_, err = w.WriteChunkedBodyDone()
+	require.NoError(t, err)
+	trailers := headers.NewHeaders()
+	trailers.Set("X-Checksum", "a\x00b")
+	require.ErrorIs(t, w.WriteTrailers(trailers), headers.ErrInvalidFieldValue)
+
+	// Test: Sanitizing replaces forbidden characters instead of failing
+	out.Reset()
+	w = newTestWriter(&out)
+	w.SanitizeHeaders(true)
+	require.NoError(t, w.WriteStatusLine(StatusCodeSuccess))
+	h = headers.NewHeaders()
+	h.Set("X-Upstream", "ok\r\nSet-Cookie: session=evil")
+	require.NoError(t, w.WriteHeaders(h))
+	assert.Equal(t, "HTTP/1.1 200 OK\r\nx-upstream: ok  Set-Cookie: session=evil\r\n\r\n", out.String())
+	assert.Equal(t, "ok\r\nSet-Cookie: session=evil", h["x-upstream"])
+}
+
+func TestAddCookie(t *testing.T) {
+	// Test: Each cookie gets its own Set-Cookie line
+	var out strings.Builder
+	w := newTestWriter(&out)
+	require.NoError(t, w.AddCookie(&cookie.Cookie{Name: "a", Value: "1", HttpOnly: true}))
+	require.NoError(t, w.WriteStatusLine(StatusCodeSuccess))
+	require.NoError(t, w.AddCookie(&cookie.Cookie{Name: "b", Value: "2", Expires: time.Date(2030, time.January, 2, 3, 4, 5, 0, time.UTC)}))
+	require.NoError(t, w.WriteHeaders(headers.NewHeaders()))
+	assert.Equal(t, "HTTP/1.1 200 OK\r\n"+
+		"set-cookie: a=1; HttpOnly\r\n"+
+		"set-cookie: b=2; Expires=Wed, 02 Jan 2030 03:04:05 GMT\r\n"+
+		"\r\n", out.String())
+
+	// Test: Too late once headers are written
+	require.Error(t, w.AddCookie(&cookie.Cookie{Name: "c", Value: "3"}))
+
+	// Test: Invalid cookie
+	w = newTestWriter(&out)
+	require.ErrorIs(t, w.AddCookie(&cookie.Cookie{Name: "c", Value: "a\r\nb"}), cookie.ErrInvalidCookie)
+}
+
+func TestWriteBodyFromFile(t *testing.T) {
+	// Test: A file section is sent over TCP after the headers, framed by
+	// Content-Length
+	path := filepath.Join(t.TempDir(), "body.txt")
+	require.NoError(t, os.WriteFile(path, []byte("skip-this-part|sent body|not sent"), 0o644))
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	_, err = f.Seek(15, io.SeekStart)
+	require.NoError(t, err)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+	received := make(chan string, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			received <- err.Error()
+			return
+		}
+		defer conn.Close()
+		b, _ := io.ReadAll(conn)
+		received <- string(b)
+	}()
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	require.NoError(t, err)
+	w := newTestWriter(conn)
+	require.NoError(t, w.WriteStatusLine(StatusCodeSuccess))
+	h := headers.NewHeaders()
+	h.Set("Content-Length", "9")
+	require.NoError(t, w.WriteHeaders(h))
+	n, err := w.WriteBodyFrom(&io.LimitedReader{R: f, N: 9})
+	require.NoError(t, err)
+	assert.Equal(t, int64(9), n)
+	conn.Close()
+	assert.Equal(t, "HTTP/1.1 200 OK\r\ncontent-length: 9\r\n\r\nsent body", <-received)
+}
+
+type upperEncoder struct {
+	w io.Writer
+}
+
+func (u upperEncoder) Write(p []byte) (int, error) {
+	return u.w.Write([]byte(strings.ToUpper(string(p))))
+}
+
+func (upperEncoder) Close() error {
+	return nil
+}
+
+func TestBodyEncoder(t *testing.T) {
+	encode := func(statusCode StatusCode, h headers.Headers) func(io.Writer) io.WriteCloser {
+		if statusCode != StatusCodeSuccess {
+			return nil
+		}
+		h.Set("Content-Encoding", "upper")
+		return func(w io.Writer) io.WriteCloser { return upperEncoder{w} }
+	}
+
+	// Test: One-shot body becomes chunked
+	var out strings.Builder
+	w := newTestWriter(&out)
+	w.SetBodyEncoder(encode)
+	require.NoError(t, w.WriteStatusLine(StatusCodeSuccess))
+	h := headers.NewHeaders()
+	h.Set("Content-Length", "5")
+	require.NoError(t, w.WriteHeaders(h))
+	_, err := w.WriteBody([]byte("hello"))
+	require.NoError(t, err)
+	assert.Contains(t, out.String(), "transfer-encoding: chunked\r\n")
+	assert.Contains(t, out.String(), "content-encoding: upper\r\n")
+	assert.NotContains(t, out.String(), "content-length")
+	assert.True(t, strings.HasSuffix(out.String(), "\r\n\r\n5\r\nHELLO\r\n0\r\n\r\n"))
+	_, ok := h.Get("Content-Encoding")
+	assert.False(t, ok, "caller's headers are not modified")
+
+	// Test: Chunked body keeps its trailers
+	out.Reset()
+	w = newTestWriter(&out)
+	w.SetBodyEncoder(encode)
+	require.NoError(t, w.WriteStatusLine(StatusCodeSuccess))
+	h = headers.NewHeaders()
+	h.Set("Transfer-Encoding", "chunked")
+	require.NoError(t, w.WriteHeaders(h))
+	_, err = w.WriteChunkedBody([]byte("ab"))
+	require.NoError(t, err)
+	_, err = w.WriteChunkedBody([]byte("c"))
+	require.NoError(t, err)
+	_, err = w.WriteChunkedBodyDone()
+	require.NoError(t, err)
+	trailers := headers.NewHeaders()
+	trailers.Set("X-Done", "yes")
+	require.NoError(t, w.WriteTrailers(trailers))
+	assert.True(t, strings.HasSuffix(out.String(), "\r\n\r\n2\r\nAB\r\n1\r\nC\r\n0\r\nx-done: yes\r\n\r\n"))
+
+	// Test: Declined by the encoder
+	out.Reset()
+	w = newTestWriter(&out)
+	w.SetBodyEncoder(encode)
+	require.NoError(t, w.WriteStatusLine(StatusCodeNotFound))
+	h = headers.NewHeaders()
+	h.Set("Content-Length", "5")
+	require.NoError(t, w.WriteHeaders(h))
+	_, err = w.WriteBody([]byte("hello"))
+	require.NoError(t, err)
+	assert.Equal(t, "HTTP/1.1 404 Not Found\r\ncontent-length: 5\r\n\r\nhello", out.String())
+}
+
+// newTestWriter returns a Writer without the automatic Date header, so tests
+// can compare whole responses.
+func newTestWriter(w io.Writer) *Writer {
+	wr := NewWriter(w)
+	wr.SetDateHeader(false)
+	return wr
+}
+
+func TestAutomaticHeaders(t *testing.T) {
+	defer func() { now = time.Now }()
+	now = func() time.Time { return time.Date(2030, time.January, 2, 3, 4, 5, 0, time.FixedZone("CET", 3600)) }
+
+	// Test: Date in IMF-fixdate and an optional Server
+	var out strings.Builder
+	w := NewWriter(&out)
+	w.SetServerHeader("httpfromtcp")
+	require.NoError(t, w.WriteStatusLine(StatusCodeSuccess))
+	require.NoError(t, w.WriteHeaders(headers.NewHeaders()))
+	assert.Contains(t, out.String(), "date: Wed, 02 Jan 2030 02:04:05 GMT\r\n")
+	assert.Contains(t, out.String(), "server: httpfromtcp\r\n")
+
+	// Test: Handler values win and the caller's headers are not modified
+	out.Reset()
+	w = NewWriter(&out)
+	w.SetServerHeader("httpfromtcp")
+	require.NoError(t, w.WriteStatusLine(StatusCodeSuccess))
+	h := headers.NewHeaders()
+	h.Set("Date", "Thu, 01 Jan 1970 00:00:00 GMT")
+	h.Set("Server", "custom")
+	require.NoError(t, w.WriteHeaders(h))
+	assert.Contains(t, out.String(), "date: Thu, 01 Jan 1970 00:00:00 GMT\r\n")
+	assert.Contains(t, out.String(), "server: custom\r\n")
+	assert.Len(t, h, 2)
+
+	// Test: Both can be suppressed
+	out.Reset()
+	w = NewWriter(&out)
+	w.SetDateHeader(false)
+	require.NoError(t, w.WriteStatusLine(StatusCodeSuccess))
+	require.NoError(t, w.WriteHeaders(headers.NewHeaders()))
+	assert.Equal(t, "HTTP/1.1 200 OK\r\n\r\n", out.String())
+
+	// Test: The formatted value is reused within a second
+	dateCache.Store(&cachedDate{unix: now().Unix(), value: "cached"})
+	assert.Equal(t, "cached", currentDate())
+	now = func() time.Time { return time.Date(2030, time.January, 2, 3, 4, 6, 0, time.UTC) }
+	assert.Equal(t, "Wed, 02 Jan 2030 03:04:06 GMT", currentDate())
+}
@@ -0,0 +1,42 @@
+package server
+
+import (
+	"net/url"
+
+	"github.com/UUest/httpfromtcp/internal/request"
+	"github.com/UUest/httpfromtcp/internal/response"
+	"github.com/UUest/httpfromtcp/internal/urlpath"
+)
+
+// CleanPath normalizes req.Target.Path with urlpath.Clean before calling
+// next. If redirect is set, requests for an unclean path are answered with a
+// 308 to the clean one instead of being rewritten in place.
+func CleanPath(next Handler, policy urlpath.TrailingSlash, redirect bool) Handler {
+	return func(w *response.Writer, req *request.Request) {
+		if req.Target.Form != request.TargetOriginForm && req.Target.Form != request.TargetAbsoluteForm {
+			next(w, req)
+			return
+		}
+		cleaned := urlpath.Clean(req.Target.Path, policy)
+		if cleaned == req.Target.Path {
+			next(w, req)
+			return
+		}
+
+		rawPath := (&url.URL{Path: cleaned}).EscapedPath()
+		if redirect {
+			location := rawPath
+			if req.Target.RawQuery != "" {
+				location += "?" + req.Target.RawQuery
+			}
+			w.WriteStatusLine(response.StatusCodePermanentRedirect)
+			h := response.GetDefaultHeaders(0)
+			h.Set("Location", location)
+			w.WriteHeaders(h)
+			return
+		}
+		req.Target.Path = cleaned
+		req.Target.RawPath = rawPath
+		next(w, req)
+	}
+}
@@ -0,0 +1,84 @@
+package server
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/UUest/httpfromtcp/internal/request"
+	"github.com/UUest/httpfromtcp/internal/response"
+)
+
+// HostMux dispatches requests to a Handler by host name. Patterns are either
+// exact names ("example.com") or wildcards ("*.example.com") that match any
+// subdomain but not the bare domain. Exact names win over wildcards, and
+// longer wildcards win over shorter ones.
+type HostMux struct {
+	exact     map[string]Handler
+	wildcards map[string]Handler
+	fallback  Handler
+}
+
+// NewHostMux returns a HostMux that sends unmatched hosts to fallback, or
+// answers 404 if fallback is nil.
+func NewHostMux(fallback Handler) *HostMux {
+	return &HostMux{
+		exact:     map[string]Handler{},
+		wildcards: map[string]Handler{},
+		fallback:  fallback,
+	}
+}
+
+func (m *HostMux) Handle(pattern string, h Handler) {
+	pattern = normalizeHostName(pattern)
+	if suffix, ok := strings.CutPrefix(pattern, "*."); ok {
+		m.wildcards[suffix] = h
+		return
+	}
+	m.exact[pattern] = h
+}
+
+// ServeRequest is a Handler, so a HostMux can be passed to Serve.
+func (m *HostMux) ServeRequest(w *response.Writer, req *request.Request) {
+	h := m.match(hostName(req.Host))
+	if h == nil {
+		writeError(w, response.StatusCodeNotFound, fmt.Errorf("no site configured for host %q", req.Host))
+		return
+	}
+	h(w, req)
+}
+
+func (m *HostMux) match(name string) Handler {
+	if h, ok := m.exact[name]; ok {
+		return h
+	}
+	// walk up the labels so the most specific wildcard is tried first
+	for rest := name; ; {
+		_, parent, ok := strings.Cut(rest, ".")
+		if !ok {
+			break
+		}
+		if h, ok := m.wildcards[parent]; ok {
+			return h
+		}
+		rest = parent
+	}
+	return m.fallback
+}
+
+// hostName strips the port from a Host value.
+func hostName(host string) string {
+	if strings.HasPrefix(host, "[") {
+		if end := strings.IndexByte(host, ']'); end != -1 {
+			return host[:end+1]
+		}
+		return host
+	}
+	if idx := strings.LastIndexByte(host, ':'); idx != -1 {
+		host = host[:idx]
+	}
+	return normalizeHostName(host)
+}
+
+func normalizeHostName(name string) string {
+	return strings.TrimSuffix(strings.ToLower(name), ".")
+}
@@ -0,0 +1,54 @@
+package server
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/UUest/httpfromtcp/internal/request"
+	"github.com/UUest/httpfromtcp/internal/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHostMux(t *testing.T) {
+	var served string
+	named := func(name string) Handler {
+		return func(_ *response.Writer, _ *request.Request) {
+			served = name
+		}
+	}
+	mux := NewHostMux(named("fallback"))
+	mux.Handle("example.com", named("apex"))
+	mux.Handle("*.example.com", named("wildcard"))
+	mux.Handle("*.api.example.com", named("api"))
+	mux.Handle("API.example.com", named("api-apex"))
+
+	serve := func(host string) string {
+		served = ""
+		req, err := request.RequestFromReader(strings.NewReader("GET / HTTP/1.1\r\nHost: " + host + "\r\n\r\n"))
+		require.NoError(t, err)
+		mux.ServeRequest(response.NewWriter(&strings.Builder{}), req)
+		return served
+	}
+
+	// Test: Exact match, ignoring port, case and trailing dot
+	assert.Equal(t, "apex", serve("Example.COM:42069"))
+	assert.Equal(t, "apex", serve("example.com."))
+
+	// Test: Wildcards match any depth, most specific first
+	assert.Equal(t, "wildcard", serve("www.example.com"))
+	assert.Equal(t, "wildcard", serve("a.b.example.com"))
+	assert.Equal(t, "api", serve("v1.api.example.com"))
+	assert.Equal(t, "api-apex", serve("api.example.com"))
+
+	// Test: Unknown host goes to the fallback
+	assert.Equal(t, "fallback", serve("example.org"))
+
+	// Test: No fallback answers 404
+	mux = NewHostMux(nil)
+	req, err := request.RequestFromReader(strings.NewReader("GET / HTTP/1.1\r\nHost: example.org\r\n\r\n"))
+	require.NoError(t, err)
+	var out strings.Builder
+	mux.ServeRequest(response.NewWriter(&out), req)
+	assert.True(t, strings.HasPrefix(out.String(), "HTTP/1.1 404 Not Found\r\n"))
+}
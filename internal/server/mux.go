@@ -0,0 +1,94 @@
+package server
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/UUest/httpfromtcp/internal/request"
+	"github.com/UUest/httpfromtcp/internal/response"
+)
+
+// Mux dispatches requests by path and method. A pattern is either an exact
+// path ("/video") or, if it ends in a slash, a subtree ("/httpbin/") that
+// matches every path below it; "/" therefore matches everything. Exact paths
+// win over subtrees, and longer subtrees over shorter ones.
+//
+// For a matched path, HEAD falls back to the GET handler and OPTIONS is
+// answered with the path's Allow list unless handlers were registered for
+// them. Other methods get 405 with the path's Allow list; methods the server
+// does not implement at all are answered with 501 by the Server before the
+// Mux runs (see WithMethods).
+type Mux struct {
+	routes map[string]map[string]Handler
+}
+
+func NewMux() *Mux {
+	return &Mux{
+		routes: map[string]map[string]Handler{},
+	}
+}
+
+func (m *Mux) Handle(method, pattern string, h Handler) {
+	if m.routes[pattern] == nil {
+		m.routes[pattern] = map[string]Handler{}
+	}
+	m.routes[pattern][method] = h
+}
+
+// ServeRequest is a Handler, so a Mux can be passed to Serve.
+func (m *Mux) ServeRequest(w *response.Writer, req *request.Request) {
+	method := req.RequestLine.Method
+	handlers := m.match(req.Target.Path)
+	if handlers == nil {
+		writeError(w, response.StatusCodeNotFound, fmt.Errorf("no route for %s", req.Target.Path))
+		return
+	}
+	if h, ok := handlers[method]; ok {
+		h(w, req)
+		return
+	}
+	if h, ok := handlers["GET"]; ok && method == "HEAD" {
+		h(w, req)
+		return
+	}
+	if method == "OPTIONS" {
+		writeAllow(w, response.StatusCodeSuccess, allowed(handlers))
+		return
+	}
+	writeAllow(w, response.StatusCodeMethodNotAllowed, allowed(handlers))
+}
+
+func (m *Mux) match(path string) map[string]Handler {
+	if handlers, ok := m.routes[path]; ok {
+		return handlers
+	}
+	var best string
+	for pattern := range m.routes {
+		if strings.HasSuffix(pattern, "/") && strings.HasPrefix(path, pattern) && len(pattern) > len(best) {
+			best = pattern
+		}
+	}
+	if best == "" {
+		return nil
+	}
+	return m.routes[best]
+}
+
+// allowed lists the methods handlers answers, including the implicit HEAD and
+// OPTIONS, in a stable order.
+func allowed(handlers map[string]Handler) []string {
+	methods := []string{"OPTIONS"}
+	for method := range handlers {
+		if method != "OPTIONS" {
+			methods = append(methods, method)
+		}
+	}
+	if _, ok := handlers["GET"]; ok {
+		if _, ok := handlers["HEAD"]; !ok {
+			methods = append(methods, "HEAD")
+		}
+	}
+	slices.Sort(methods)
+	return methods
+}
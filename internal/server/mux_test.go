@@ -0,0 +1,73 @@
+package server
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/UUest/httpfromtcp/internal/request"
+	"github.com/UUest/httpfromtcp/internal/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMux(t *testing.T) {
+	var served string
+	named := func(name string) Handler {
+		return func(_ *response.Writer, _ *request.Request) {
+			served = name
+		}
+	}
+	mux := NewMux()
+	mux.Handle("GET", "/", named("root"))
+	mux.Handle("GET", "/video", named("video"))
+	mux.Handle("GET", "/api/", named("api-get"))
+	mux.Handle("POST", "/api/", named("api-post"))
+	mux.Handle("GET", "/api/v2/", named("v2"))
+	mux.Handle("DELETE", "/items", named("delete"))
+
+	serve := func(method, path string) (string, string) {
+		served = ""
+		req, err := request.RequestFromReader(strings.NewReader(method + " " + path + " HTTP/1.1\r\nHost: localhost:42069\r\n\r\n"))
+		require.NoError(t, err)
+		var out strings.Builder
+		mux.ServeRequest(response.NewWriter(&out), req)
+		return served, out.String()
+	}
+
+	// Test: Exact paths win over subtrees, longer subtrees over shorter
+	name, _ := serve("GET", "/video")
+	assert.Equal(t, "video", name)
+	name, _ = serve("GET", "/video/extra")
+	assert.Equal(t, "root", name)
+	name, _ = serve("POST", "/api/users")
+	assert.Equal(t, "api-post", name)
+	name, _ = serve("GET", "/api/v2/users")
+	assert.Equal(t, "v2", name)
+
+	// Test: HEAD uses the GET handler
+	name, _ = serve("HEAD", "/video")
+	assert.Equal(t, "video", name)
+
+	// Test: OPTIONS lists the route's methods
+	name, out := serve("OPTIONS", "/api/x")
+	assert.Empty(t, name)
+	assert.True(t, strings.HasPrefix(out, "HTTP/1.1 200 OK\r\n"))
+	assert.Contains(t, out, "allow: GET, HEAD, OPTIONS, POST\r\n")
+
+	// Test: Method the route does not handle
+	name, out = serve("POST", "/video")
+	assert.Empty(t, name)
+	assert.True(t, strings.HasPrefix(out, "HTTP/1.1 405 Method Not Allowed\r\n"))
+	assert.Contains(t, out, "allow: GET, HEAD, OPTIONS\r\n")
+
+	// Test: Method no route supports is still 405, not 501
+	_, out = serve("PUT", "/video")
+	assert.True(t, strings.HasPrefix(out, "HTTP/1.1 405 Method Not Allowed\r\n"))
+	assert.Contains(t, out, "allow: GET, HEAD, OPTIONS\r\n")
+
+	// Test: No matching route
+	mux = NewMux()
+	mux.Handle("GET", "/only", named("only"))
+	_, out = serve("GET", "/other")
+	assert.True(t, strings.HasPrefix(out, "HTTP/1.1 404 Not Found\r\n"))
+}
@@ -1,24 +1,92 @@
 package server
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"net"
+	"slices"
+	"strings"
 	"sync/atomic"
 
+	"github.com/UUest/httpfromtcp/internal/jsonapi"
 	"github.com/UUest/httpfromtcp/internal/request"
 	"github.com/UUest/httpfromtcp/internal/response"
 )
 
 type Handler func(w *response.Writer, req *request.Request)
 
+// ExpectContinuePolicy controls when the server answers Expect: 100-continue.
+type ExpectContinuePolicy int
+
+const (
+	// ExpectContinueImmediate sends 100 Continue and reads the whole body
+	// before the handler runs, so handlers can use req.Body directly.
+	ExpectContinueImmediate ExpectContinuePolicy = iota
+	// ExpectContinueOnRead defers 100 Continue until the handler calls
+	// req.ReadBody. Handlers can reject the request by writing a final status
+	// without reading the body.
+	ExpectContinueOnRead
+)
+
 type Server struct {
-	listener net.Listener
-	handler  Handler
-	closed   atomic.Bool
+	listener       net.Listener
+	handler        Handler
+	closed         atomic.Bool
+	expectContinue ExpectContinuePolicy
+	requestOptions request.Options
+	methods        []string
+	serverHeader   string
+	maxBodySize    int64
+}
+
+// DefaultMethods are the methods a Server accepts unless WithMethods says
+// otherwise.
+var DefaultMethods = []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
+
+type Option func(*Server)
+
+func WithExpectContinue(policy ExpectContinuePolicy) Option {
+	return func(s *Server) {
+		s.expectContinue = policy
+	}
+}
+
+// WithRequestOptions relaxes request parsing, e.g. to unfold obs-fold header
+// lines from legacy clients instead of rejecting them.
+func WithRequestOptions(opts request.Options) Option {
+	return func(s *Server) {
+		s.requestOptions = opts
+	}
 }
 
-func Serve(port int, handler Handler) (*Server, error) {
+// WithMethods sets the methods the server implements. Requests with any other
+// method are answered with 501 before the handler runs, and "OPTIONS *"
+// lists these methods in Allow.
+func WithMethods(methods ...string) Option {
+	return func(s *Server) {
+		s.methods = methods
+	}
+}
+
+// WithServerHeader sends a Server header with every response. Handlers can
+// still override or drop it through the response.Writer.
+func WithServerHeader(value string) Option {
+	return func(s *Server) {
+		s.serverHeader = value
+	}
+}
+
+// WithMaxBodySize limits the bodies the server reads before the handler
+// runs. Reading stops as soon as a body grows past n bytes and the request
+// is answered with 413. Zero, the default, means no limit.
+func WithMaxBodySize(n int64) Option {
+	return func(s *Server) {
+		s.maxBodySize = n
+	}
+}
+
+func Serve(port int, handler Handler, opts ...Option) (*Server, error) {
 	l, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
 	if err != nil {
 		return nil, err
@@ -26,6 +94,10 @@ func Serve(port int, handler Handler) (*Server, error) {
 	s := &Server{
 		listener: l,
 		handler:  handler,
+		methods:  DefaultMethods,
+	}
+	for _, opt := range opts {
+		opt(s)
 	}
 	go s.Listen()
 	return s, nil
@@ -54,15 +126,79 @@ func (s *Server) Listen() {
 }
 
 func (s *Server) handle(conn net.Conn) {
-	defer conn.Close()
 	w := response.NewWriter(conn)
-	req, err := request.RequestFromReader(conn)
+	defer func() {
+		// a hijacked connection belongs to the handler now
+		if !w.Hijacked() {
+			conn.Close()
+		}
+	}()
+	w.SetServerHeader(s.serverHeader)
+	req, err := request.HeadersFromReaderWithOptions(conn, s.requestOptions)
 	if err != nil {
-		w.WriteStatusLine(response.StatusCodeBadRequest)
-		body := []byte(fmt.Sprintf("Error parsing request: %v", err))
-		w.WriteHeaders(response.GetDefaultHeaders(len(body)))
-		w.WriteBody(body)
+		statusCode := response.StatusCodeBadRequest
+		switch {
+		case errors.Is(err, request.ErrUnsupportedVersion):
+			statusCode = response.StatusCodeVersionNotSupported
+		case errors.Is(err, request.ErrUnsupportedTransferEncoding):
+			statusCode = response.StatusCodeNotImplemented
+		}
+		writeError(w, statusCode, fmt.Errorf("Error parsing request: %v", err))
+		return
+	}
+	w.SetRequestVersion(req.RequestLine.HttpVersion)
+	if req.RequestLine.Method == "HEAD" {
+		w.SuppressBody(true)
+	}
+	if !slices.Contains(s.methods, req.RequestLine.Method) {
+		writeError(w, response.StatusCodeNotImplemented, fmt.Errorf("method %s is not implemented", req.RequestLine.Method))
+		return
+	}
+
+	expectsContinue := req.ExpectsContinue()
+	if _, ok := req.Headers.Get("Expect"); ok && !expectsContinue {
+		writeError(w, response.StatusCodeExpectationFailed, fmt.Errorf("unsupported expectation"))
+		return
+	}
+	if expectsContinue {
+		req.BeforeBodyRead(w.WriteContinue)
+	}
+	// multipart bodies are left on the connection for MultipartReader
+	mediaType, _, _ := req.Headers.ContentType()
+	streamed := strings.HasPrefix(mediaType, "multipart/")
+	if !streamed && (!expectsContinue || s.expectContinue == ExpectContinueImmediate) {
+		if s.maxBodySize > 0 {
+			_, err = req.ReadBodyLimit(s.maxBodySize)
+		} else {
+			_, err = req.ReadBody()
+		}
+		if errors.Is(err, request.ErrBodyTooLarge) {
+			writeError(w, response.StatusCodeContentTooLarge, err)
+			return
+		}
+		if err != nil {
+			writeError(w, response.StatusCodeBadRequest, fmt.Errorf("Error parsing request: %v", err))
+			return
+		}
+	}
+	if req.Target.Form == request.TargetAsteriskForm {
+		// "OPTIONS *" asks about the server as a whole
+		writeAllow(w, response.StatusCodeSuccess, s.methods)
 		return
 	}
 	s.handler(w, req)
 }
+
+// writeAllow answers with an empty body and the given methods in Allow.
+func writeAllow(w *response.Writer, statusCode response.StatusCode, methods []string) {
+	w.WriteStatusLine(statusCode)
+	h := response.GetDefaultHeaders(0)
+	h.Set("Allow", strings.Join(methods, ", "))
+	w.WriteHeaders(h)
+}
+
+// writeError answers a request the server could not hand to the handler with
+// an application/problem+json body.
+func writeError(w *response.Writer, statusCode response.StatusCode, err error) {
+	jsonapi.WriteProblem(w, jsonapi.NewProblem(statusCode, err.Error()))
+}
@@ -0,0 +1,161 @@
+package server
+
+import (
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/UUest/httpfromtcp/internal/request"
+	"github.com/UUest/httpfromtcp/internal/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// roundTrip sends raw to a server on a free port and returns the whole
+// response, which ends when the server closes the connection.
+func roundTrip(t *testing.T, handler Handler, raw string, opts ...Option) string {
+	t.Helper()
+	s, err := Serve(0, handler, opts...)
+	require.NoError(t, err)
+	defer s.Close()
+	conn, err := net.Dial("tcp", s.listener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte(raw))
+	require.NoError(t, err)
+	b, err := io.ReadAll(conn)
+	require.NoError(t, err)
+	return string(b)
+}
+
+func helloHandler(w *response.Writer, _ *request.Request) {
+	body := []byte("hello")
+	w.WriteStatusLine(response.StatusCodeSuccess)
+	w.WriteHeaders(response.GetDefaultHeaders(len(body)))
+	w.WriteBody(body)
+}
+
+func TestMethodHandling(t *testing.T) {
+	// Test: HEAD keeps Content-Length but drops the body
+	out := roundTrip(t, helloHandler, "HEAD / HTTP/1.1\r\nHost: localhost\r\n\r\n")
+	assert.True(t, strings.HasPrefix(out, "HTTP/1.1 200 OK\r\n"))
+	assert.Contains(t, out, "content-length: 5\r\n")
+	assert.True(t, strings.HasSuffix(out, "\r\n\r\n"))
+
+	// Test: OPTIONS * lists the server's methods
+	out = roundTrip(t, helloHandler, "OPTIONS * HTTP/1.1\r\nHost: localhost\r\n\r\n", WithMethods("GET", "HEAD", "OPTIONS"))
+	assert.True(t, strings.HasPrefix(out, "HTTP/1.1 200 OK\r\n"))
+	assert.Contains(t, out, "allow: GET, HEAD, OPTIONS\r\n")
+	assert.NotContains(t, out, "hello")
+
+	// Test: Methods outside the set are not implemented
+	out = roundTrip(t, helloHandler, "TRACE / HTTP/1.1\r\nHost: localhost\r\n\r\n")
+	assert.True(t, strings.HasPrefix(out, "HTTP/1.1 501 Not Implemented\r\n"))
+	out = roundTrip(t, helloHandler, "POST / HTTP/1.1\r\nHost: localhost\r\nContent-Length: 0\r\n\r\n", WithMethods("GET", "HEAD"))
+	assert.True(t, strings.HasPrefix(out, "HTTP/1.1 501 Not Implemented\r\n"))
+
+	// Test: Implemented methods a route does not handle get 405 from a Mux
+	mux := NewMux()
+	mux.Handle("GET", "/", helloHandler)
+	out = roundTrip(t, mux.ServeRequest, "POST / HTTP/1.1\r\nHost: localhost\r\nContent-Length: 0\r\n\r\n")
+	assert.True(t, strings.HasPrefix(out, "HTTP/1.1 405 Method Not Allowed\r\n"))
+	assert.Contains(t, out, "allow: GET, HEAD, OPTIONS\r\n")
+}
+
+func TestAutomaticHeaders(t *testing.T) {
+	// Test: Date is always sent and Server when configured
+	out := roundTrip(t, helloHandler, "GET / HTTP/1.1\r\nHost: localhost\r\n\r\n", WithServerHeader("httpfromtcp"))
+	assert.Regexp(t, `\r\ndate: [A-Z][a-z]{2}, \d{2} [A-Z][a-z]{2} \d{4} \d{2}:\d{2}:\d{2} GMT\r\n`, out)
+	assert.Contains(t, out, "\r\nserver: httpfromtcp\r\n")
+
+	// Test: Handlers can drop both
+	quiet := func(w *response.Writer, req *request.Request) {
+		w.SetDateHeader(false)
+		w.SetServerHeader("")
+		helloHandler(w, req)
+	}
+	out = roundTrip(t, quiet, "GET / HTTP/1.1\r\nHost: localhost\r\n\r\n", WithServerHeader("httpfromtcp"))
+	assert.NotContains(t, out, "date:")
+	assert.NotContains(t, out, "server:")
+}
+
+func TestMultipartIsStreamed(t *testing.T) {
+	// Test: Multipart bodies are left for the handler to stream
+	body := "--b1\r\nContent-Disposition: form-data; name=\"a\"\r\n\r\nvalue\r\n--b1--\r\n"
+	handler := func(w *response.Writer, req *request.Request) {
+		assert.Empty(t, req.Body)
+		mr, err := req.MultipartReader()
+		require.NoError(t, err)
+		part, err := mr.NextPart()
+		require.NoError(t, err)
+		value, err := io.ReadAll(part)
+		require.NoError(t, err)
+		w.WriteStatusLine(response.StatusCodeSuccess)
+		w.WriteHeaders(response.GetDefaultHeaders(len(value)))
+		w.WriteBody(value)
+	}
+	out := roundTrip(t, handler, "POST /upload HTTP/1.1\r\nHost: localhost\r\n"+
+		"Content-Type: multipart/form-data; boundary=b1\r\n"+
+		"Content-Length: "+strconv.Itoa(len(body))+"\r\n\r\n"+body)
+	assert.True(t, strings.HasPrefix(out, "HTTP/1.1 200 OK\r\n"))
+	assert.True(t, strings.HasSuffix(out, "\r\n\r\nvalue"))
+}
+
+func TestHijackedConnStaysOpen(t *testing.T) {
+	// Test: A hijacked connection outlives the handler
+	returned := make(chan struct{})
+	s, err := Serve(0, func(w *response.Writer, _ *request.Request) {
+		defer close(returned)
+		conn, err := w.Hijack()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer conn.Close()
+			<-returned
+			b := make([]byte, 4)
+			_, err := io.ReadFull(conn, b)
+			if err != nil {
+				return
+			}
+			conn.Write(b)
+		}()
+	})
+	require.NoError(t, err)
+	defer s.Close()
+
+	conn, err := net.Dial("tcp", s.listener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("GET / HTTP/1.1\r\nHost: localhost\r\n\r\n"))
+	require.NoError(t, err)
+	<-returned
+	_, err = conn.Write([]byte("ping"))
+	require.NoError(t, err)
+	b, err := io.ReadAll(conn)
+	require.NoError(t, err)
+	assert.Equal(t, "ping", string(b))
+}
+
+func TestMaxBodySize(t *testing.T) {
+	// Test: A chunked body over the limit is refused before it is all sent
+	s, err := Serve(0, helloHandler, WithMaxBodySize(10))
+	require.NoError(t, err)
+	defer s.Close()
+	conn, err := net.Dial("tcp", s.listener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("POST / HTTP/1.1\r\nHost: localhost\r\n" +
+		"Content-Type: application/x-www-form-urlencoded\r\nTransfer-Encoding: chunked\r\n\r\n" +
+		"14\r\na=123456789012345678\r\n"))
+	require.NoError(t, err)
+	b, err := io.ReadAll(conn)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(b), "HTTP/1.1 413 Content Too Large\r\n"))
+
+	// Test: Bodies within the limit reach the handler
+	out := roundTrip(t, helloHandler, "POST / HTTP/1.1\r\nHost: localhost\r\nContent-Length: 3\r\n\r\na=1", WithMaxBodySize(10))
+	assert.True(t, strings.HasPrefix(out, "HTTP/1.1 200 OK\r\n"))
+}
@@ -0,0 +1,152 @@
+package sse
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/UUest/httpfromtcp/internal/headers"
+	"github.com/UUest/httpfromtcp/internal/request"
+	"github.com/UUest/httpfromtcp/internal/response"
+)
+
+const DefaultKeepAlive = 15 * time.Second
+
+// Event is a single server-sent event. Empty fields are omitted.
+type Event struct {
+	ID    string
+	Event string
+	Data  string
+	Retry time.Duration
+}
+
+// Writer streams text/event-stream over a chunked response. Every event is
+// written as its own chunk, so it reaches the client immediately.
+type Writer struct {
+	w      *response.Writer
+	mu     sync.Mutex
+	done   chan struct{}
+	closed bool
+}
+
+// NewWriter writes the 200 status line and event-stream headers, then starts
+// sending keep-alive comments every keepAlive. A keepAlive of zero disables
+// them.
+func NewWriter(w *response.Writer, keepAlive time.Duration) (*Writer, error) {
+	err := w.WriteStatusLine(response.StatusCodeSuccess)
+	if err != nil {
+		return nil, err
+	}
+	h := response.GetDefaultHeaders(0)
+	h.Remove("Content-Length")
+	h.Override("Content-Type", "text/event-stream")
+	h.Override("Cache-Control", "no-cache")
+	h.Override("Transfer-Encoding", "chunked")
+	err = w.WriteHeaders(h)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Writer{
+		w:    w,
+		done: make(chan struct{}),
+	}
+	if keepAlive > 0 {
+		go s.keepAlive(keepAlive)
+	}
+	return s, nil
+}
+
+// LastEventID returns the Last-Event-ID header a reconnecting client sent, or
+// an empty string.
+func LastEventID(req *request.Request) string {
+	id, _ := req.Headers.Get("Last-Event-ID")
+	return id
+}
+
+// Send formats e and writes it to the client.
+func (s *Writer) Send(e Event) error {
+	b, err := formatEvent(e)
+	if err != nil {
+		return err
+	}
+	return s.write(b)
+}
+
+// Comment writes a comment line, which clients ignore.
+func (s *Writer) Comment(text string) error {
+	if strings.ContainsAny(text, "\r\n") {
+		return fmt.Errorf("comment must not contain line breaks")
+	}
+	return s.write([]byte(": " + text + "\n\n"))
+}
+
+// Close stops the keep-alives and terminates the chunked body.
+func (s *Writer) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	close(s.done)
+	_, err := s.w.WriteChunkedBodyDone()
+	if err != nil {
+		return err
+	}
+	return s.w.WriteTrailers(headers.NewHeaders())
+}
+
+func (s *Writer) write(b []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return fmt.Errorf("event stream is closed")
+	}
+	_, err := s.w.WriteChunkedBody(b)
+	return err
+}
+
+func (s *Writer) keepAlive(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			err := s.Comment("keep-alive")
+			if err != nil {
+				return
+			}
+		}
+	}
+}
+
+func formatEvent(e Event) ([]byte, error) {
+	if strings.ContainsAny(e.Event, "\r\n") {
+		return nil, fmt.Errorf("event name must not contain line breaks")
+	}
+	if strings.ContainsAny(e.ID, "\r\n\x00") {
+		return nil, fmt.Errorf("event id must not contain line breaks or NUL")
+	}
+
+	var b strings.Builder
+	if e.ID != "" {
+		fmt.Fprintf(&b, "id: %s\n", e.ID)
+	}
+	if e.Event != "" {
+		fmt.Fprintf(&b, "event: %s\n", e.Event)
+	}
+	if e.Retry > 0 {
+		fmt.Fprintf(&b, "retry: %d\n", e.Retry.Milliseconds())
+	}
+	data := strings.ReplaceAll(e.Data, "\r\n", "\n")
+	data = strings.ReplaceAll(data, "\r", "\n")
+	for _, line := range strings.Split(data, "\n") {
+		fmt.Fprintf(&b, "data: %s\n", line)
+	}
+	b.WriteString("\n")
+	return []byte(b.String()), nil
+}
@@ -0,0 +1,116 @@
+package sse
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/UUest/httpfromtcp/internal/compress"
+	"github.com/UUest/httpfromtcp/internal/request"
+	"github.com/UUest/httpfromtcp/internal/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestFormatEvent(t *testing.T) {
+	// Test: All fields with multi-line data
+	b, err := formatEvent(Event{ID: "42", Event: "update", Data: "line one\nline two", Retry: 3 * time.Second})
+	require.NoError(t, err)
+	assert.Equal(t, "id: 42\nevent: update\nretry: 3000\ndata: line one\ndata: line two\n\n", string(b))
+
+	// Test: Data only
+	b, err = formatEvent(Event{Data: "hello"})
+	require.NoError(t, err)
+	assert.Equal(t, "data: hello\n\n", string(b))
+
+	// Test: Newline in event name
+	_, err = formatEvent(Event{Event: "bad\nname"})
+	require.Error(t, err)
+}
+
+func TestWriter(t *testing.T) {
+	// Test: Headers, one event and termination
+	var out lockedBuffer
+	s, err := NewWriter(response.NewWriter(&out), 0)
+	require.NoError(t, err)
+	require.NoError(t, s.Send(Event{Data: "hi"}))
+	require.NoError(t, s.Close())
+
+	got := out.String()
+	assert.True(t, strings.HasPrefix(got, "HTTP/1.1 200 OK\r\n"))
+	assert.Contains(t, got, "content-type: text/event-stream\r\n")
+	assert.Contains(t, got, "transfer-encoding: chunked\r\n")
+	assert.NotContains(t, got, "content-length")
+	assert.True(t, strings.HasSuffix(got, "\r\n\r\na\r\ndata: hi\n\n\r\n0\r\n\r\n"))
+	require.Error(t, s.Send(Event{Data: "late"}))
+
+	// Test: Keep-alive comments
+	var pinged lockedBuffer
+	s, err = NewWriter(response.NewWriter(&pinged), 10*time.Millisecond)
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		return strings.Contains(pinged.String(), ": keep-alive\n\n")
+	}, time.Second, 5*time.Millisecond)
+	require.NoError(t, s.Close())
+}
+
+func TestWriterUnderCompression(t *testing.T) {
+	// Test: Events pass through compress.Middleware uncompressed and at once
+	proceed := make(chan struct{})
+	h := compress.Middleware(func(w *response.Writer, _ *request.Request) {
+		s, err := NewWriter(w, 0)
+		if err != nil {
+			return
+		}
+		s.Send(Event{Data: "first"})
+		<-proceed
+		s.Close()
+	})
+	req, err := request.RequestFromReader(strings.NewReader("GET /events HTTP/1.1\r\nHost: localhost:42069\r\nAccept-Encoding: gzip\r\n\r\n"))
+	require.NoError(t, err)
+	pr, pw := io.Pipe()
+	go func() {
+		h(response.NewWriter(pw), req)
+		pw.Close()
+	}()
+
+	br := bufio.NewReader(pr)
+	var got strings.Builder
+	for !strings.HasSuffix(got.String(), "data: first\n\n") {
+		line, err := br.ReadString('\n')
+		require.NoError(t, err)
+		got.WriteString(line)
+	}
+	assert.NotContains(t, got.String(), "content-encoding")
+	close(proceed)
+	_, err = io.ReadAll(br)
+	require.NoError(t, err)
+}
+
+func TestLastEventID(t *testing.T) {
+	// Test: Resuming client
+	req, err := request.RequestFromReader(strings.NewReader("GET /events HTTP/1.1\r\nHost: localhost:42069\r\nLast-Event-ID: 7\r\n\r\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "7", LastEventID(req))
+}
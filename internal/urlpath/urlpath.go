@@ -0,0 +1,121 @@
+package urlpath
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// TrailingSlash decides what Clean does with a trailing slash.
+type TrailingSlash int
+
+const (
+	// TrailingSlashKeep keeps a trailing slash if the input had one.
+	TrailingSlashKeep TrailingSlash = iota
+	// TrailingSlashAdd makes every path end in a slash.
+	TrailingSlashAdd
+	// TrailingSlashRemove strips the trailing slash from every path but "/".
+	TrailingSlashRemove
+)
+
+var ErrEscapesRoot = errors.New("path escapes root")
+
+// RemoveDotSegments implements the remove_dot_segments algorithm from
+// RFC 3986 section 5.2.4.
+func RemoveDotSegments(p string) string {
+	var out []string
+	for p != "" {
+		switch {
+		case strings.HasPrefix(p, "../"):
+			p = p[3:]
+		case strings.HasPrefix(p, "./"):
+			p = p[2:]
+		case strings.HasPrefix(p, "/./"):
+			p = p[2:]
+		case p == "/.":
+			p = "/"
+		case strings.HasPrefix(p, "/../"):
+			p = p[3:]
+			if len(out) > 0 {
+				out = out[:len(out)-1]
+			}
+		case p == "/..":
+			p = "/"
+			if len(out) > 0 {
+				out = out[:len(out)-1]
+			}
+		case p == "." || p == "..":
+			p = ""
+		default:
+			start := 0
+			if p[0] == '/' {
+				start = 1
+			}
+			end := strings.IndexByte(p[start:], '/')
+			if end == -1 {
+				end = len(p)
+			} else {
+				end += start
+			}
+			out = append(out, p[:end])
+			p = p[end:]
+		}
+	}
+	return strings.Join(out, "")
+}
+
+// Clean returns the canonical form of a decoded URL path: rooted, with
+// duplicate slashes collapsed, dot-segments removed and the trailing slash
+// handled according to policy. The result never contains ".." segments.
+func Clean(p string, policy TrailingSlash) string {
+	trailing := strings.HasSuffix(p, "/") || strings.HasSuffix(p, "/.") || strings.HasSuffix(p, "/..")
+
+	var b strings.Builder
+	b.WriteByte('/')
+	last := byte('/')
+	for i := 0; i < len(p); i++ {
+		if p[i] == '/' && last == '/' {
+			continue
+		}
+		b.WriteByte(p[i])
+		last = p[i]
+	}
+	cleaned := RemoveDotSegments(b.String())
+	if cleaned == "" {
+		cleaned = "/"
+	}
+
+	switch policy {
+	case TrailingSlashAdd:
+		trailing = true
+	case TrailingSlashRemove:
+		trailing = false
+	}
+	cleaned = strings.TrimSuffix(cleaned, "/")
+	if trailing || cleaned == "" {
+		cleaned += "/"
+	}
+	return cleaned
+}
+
+// Join maps a decoded URL path onto the filesystem below root. The path is
+// cleaned first, so the result always stays inside root. Paths containing NUL
+// bytes, or the OS path separator where it is not '/', are rejected.
+// Symbolic links inside root are not resolved.
+func Join(root, p string) (string, error) {
+	if strings.IndexByte(p, 0) != -1 {
+		return "", fmt.Errorf("%w: NUL byte in path", ErrEscapesRoot)
+	}
+	if filepath.Separator != '/' && strings.ContainsRune(p, filepath.Separator) {
+		return "", fmt.Errorf("%w: path contains %q", ErrEscapesRoot, filepath.Separator)
+	}
+	cleaned := Clean(p, TrailingSlashRemove)
+	full := filepath.Join(root, filepath.FromSlash(cleaned))
+
+	rel, err := filepath.Rel(root, full)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%w: %q", ErrEscapesRoot, p)
+	}
+	return full, nil
+}
@@ -0,0 +1,61 @@
+package urlpath
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRemoveDotSegments(t *testing.T) {
+	// Test: Examples from RFC 3986 section 5.2.4
+	assert.Equal(t, "/a/g", RemoveDotSegments("/a/b/c/./../../g"))
+	assert.Equal(t, "mid/6", RemoveDotSegments("mid/content=5/../6"))
+
+	// Test: Cannot climb above the root
+	assert.Equal(t, "/etc/passwd", RemoveDotSegments("/../../etc/passwd"))
+	assert.Equal(t, "/", RemoveDotSegments("/.."))
+}
+
+func TestClean(t *testing.T) {
+	// Test: Duplicate slashes and dot-segments
+	assert.Equal(t, "/a/c", Clean("//a/./b/../c", TrailingSlashKeep))
+	assert.Equal(t, "/", Clean("", TrailingSlashKeep))
+	assert.Equal(t, "/", Clean("/../..", TrailingSlashKeep))
+
+	// Test: Decoded %2F..%2F is treated like any other dot-segment
+	assert.Equal(t, "/secret", Clean("/public/../secret", TrailingSlashKeep))
+
+	// Test: Trailing slash policies
+	assert.Equal(t, "/dir/", Clean("/dir/", TrailingSlashKeep))
+	assert.Equal(t, "/dir/", Clean("/dir/sub/..", TrailingSlashKeep))
+	assert.Equal(t, "/dir/", Clean("/dir", TrailingSlashAdd))
+	assert.Equal(t, "/dir", Clean("/dir/", TrailingSlashRemove))
+	assert.Equal(t, "/", Clean("/", TrailingSlashRemove))
+}
+
+func TestJoin(t *testing.T) {
+	root := filepath.FromSlash("/srv/www")
+
+	// Test: Plain path
+	full, err := Join(root, "/css/site.css")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.FromSlash("/srv/www/css/site.css"), full)
+
+	// Test: Traversal attempts stay inside root
+	for _, p := range []string{"/../../etc/passwd", "../etc/passwd", "/a/../../../etc/passwd", "//..//..//etc/passwd"} {
+		full, err = Join(root, p)
+		require.NoError(t, err)
+		assert.Equal(t, filepath.FromSlash("/srv/www/etc/passwd"), full, p)
+	}
+
+	// Test: Root itself
+	full, err = Join(root, "/")
+	require.NoError(t, err)
+	assert.Equal(t, root, full)
+
+	// Test: NUL byte
+	_, err = Join(root, "/index.html\x00.png")
+	require.ErrorIs(t, err, ErrEscapesRoot)
+}
@@ -0,0 +1,373 @@
+package websocket
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+type MessageType int
+
+const (
+	TextMessage   MessageType = 1
+	BinaryMessage MessageType = 2
+)
+
+type opcode byte
+
+const (
+	opContinuation opcode = 0x0
+	opText         opcode = 0x1
+	opBinary       opcode = 0x2
+	opClose        opcode = 0x8
+	opPing         opcode = 0x9
+	opPong         opcode = 0xA
+)
+
+// Close status codes from RFC 6455 section 7.4.1.
+const (
+	CloseNormalClosure    = 1000
+	CloseGoingAway        = 1001
+	CloseProtocolError    = 1002
+	CloseUnsupportedData  = 1003
+	CloseNoStatusReceived = 1005
+	CloseInvalidPayload   = 1007
+	ClosePolicyViolation  = 1008
+	CloseMessageTooBig    = 1009
+	CloseInternalError    = 1011
+)
+
+const (
+	defaultReadLimit      = 1 << 20
+	maxControlPayload     = 125
+	closeHandshakeTimeout = 5 * time.Second
+)
+
+var (
+	ErrMessageTooBig = errors.New("websocket: message exceeds read limit")
+	ErrProtocol      = errors.New("websocket: protocol error")
+	ErrClosed        = errors.New("websocket: connection closed")
+)
+
+// CloseError is returned by ReadMessage once the peer has sent a close frame.
+type CloseError struct {
+	Code   int
+	Reason string
+}
+
+func (e *CloseError) Error() string {
+	return fmt.Sprintf("websocket: close %d %s", e.Code, e.Reason)
+}
+
+// Conn is the server side of a WebSocket connection. ReadMessage must not be
+// called concurrently with itself or CloseHandshake; writes are safe to call
+// from any goroutine.
+type Conn struct {
+	conn      net.Conn
+	reader    *bufio.Reader
+	readLimit int64
+	fragment  int
+
+	writeMu   sync.Mutex
+	closeSent bool
+}
+
+func newConn(conn net.Conn) *Conn {
+	return &Conn{
+		conn:      conn,
+		reader:    bufio.NewReader(conn),
+		readLimit: defaultReadLimit,
+	}
+}
+
+// SetReadLimit sets the maximum size of a reassembled message. Messages that
+// are larger cause the connection to be closed with CloseMessageTooBig.
+func (c *Conn) SetReadLimit(limit int64) {
+	c.readLimit = limit
+}
+
+// SetWriteFragmentSize makes WriteMessage split payloads into frames of at
+// most size bytes. Zero (the default) sends every message as a single frame.
+func (c *Conn) SetWriteFragmentSize(size int) {
+	c.fragment = size
+}
+
+// Close closes the underlying connection without a close handshake.
+func (c *Conn) Close() error {
+	return c.conn.Close()
+}
+
+// ReadMessage returns the next data message, reassembling fragments and
+// answering pings along the way.
+func (c *Conn) ReadMessage() (MessageType, []byte, error) {
+	var (
+		messageType MessageType
+		message     []byte
+		inMessage   bool
+	)
+	for {
+		f, err := c.readFrame()
+		if err != nil {
+			return 0, nil, c.fail(err)
+		}
+		switch f.opcode {
+		case opPing:
+			err = c.writeFrame(true, opPong, f.payload)
+			if err != nil {
+				return 0, nil, err
+			}
+			continue
+		case opPong:
+			continue
+		case opClose:
+			closeErr, err := parseClosePayload(f.payload)
+			if err != nil {
+				return 0, nil, c.fail(err)
+			}
+			code := closeErr.Code
+			if code == CloseNoStatusReceived {
+				code = CloseNormalClosure
+			}
+			c.WriteClose(code, "")
+			return 0, nil, closeErr
+		case opText, opBinary:
+			if inMessage {
+				return 0, nil, c.fail(fmt.Errorf("%w: new message before previous one finished", ErrProtocol))
+			}
+			inMessage = true
+			messageType = MessageType(f.opcode)
+		case opContinuation:
+			if !inMessage {
+				return 0, nil, c.fail(fmt.Errorf("%w: continuation without a message", ErrProtocol))
+			}
+		}
+
+		if int64(len(message))+int64(len(f.payload)) > c.readLimit {
+			return 0, nil, c.fail(ErrMessageTooBig)
+		}
+		message = append(message, f.payload...)
+		if !f.fin {
+			continue
+		}
+		if messageType == TextMessage && !utf8.Valid(message) {
+			return 0, nil, c.fail(errInvalidUTF8)
+		}
+		return messageType, message, nil
+	}
+}
+
+var errInvalidUTF8 = errors.New("websocket: invalid UTF-8 in text message")
+
+// fail sends the close frame matching err and returns err.
+func (c *Conn) fail(err error) error {
+	switch {
+	case errors.Is(err, ErrMessageTooBig):
+		c.WriteClose(CloseMessageTooBig, "")
+	case errors.Is(err, errInvalidUTF8):
+		c.WriteClose(CloseInvalidPayload, "")
+	case errors.Is(err, ErrProtocol):
+		c.WriteClose(CloseProtocolError, "")
+	}
+	return err
+}
+
+// WriteMessage sends data as a single message, fragmented according to
+// SetWriteFragmentSize.
+func (c *Conn) WriteMessage(messageType MessageType, data []byte) error {
+	if messageType != TextMessage && messageType != BinaryMessage {
+		return fmt.Errorf("websocket: invalid message type %d", messageType)
+	}
+	op := opcode(messageType)
+	if c.fragment <= 0 || len(data) <= c.fragment {
+		return c.writeFrame(true, op, data)
+	}
+	for len(data) > c.fragment {
+		err := c.writeFrame(false, op, data[:c.fragment])
+		if err != nil {
+			return err
+		}
+		data = data[c.fragment:]
+		op = opContinuation
+	}
+	return c.writeFrame(true, op, data)
+}
+
+// Ping sends a ping frame with the given application data.
+func (c *Conn) Ping(data []byte) error {
+	if len(data) > maxControlPayload {
+		return fmt.Errorf("websocket: ping payload too large")
+	}
+	return c.writeFrame(true, opPing, data)
+}
+
+// WriteClose sends a close frame. Only the first call has an effect.
+func (c *Conn) WriteClose(code int, reason string) error {
+	payload := make([]byte, 2, 2+len(reason))
+	binary.BigEndian.PutUint16(payload, uint16(code))
+	payload = append(payload, reason...)
+	if len(payload) > maxControlPayload {
+		return fmt.Errorf("websocket: close reason too long")
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if c.closeSent {
+		return nil
+	}
+	c.closeSent = true
+	_, err := c.conn.Write(appendFrame(nil, true, opClose, payload))
+	return err
+}
+
+// CloseHandshake sends a close frame, waits for the peer's close frame and
+// closes the connection.
+func (c *Conn) CloseHandshake(code int, reason string) error {
+	defer c.conn.Close()
+	err := c.WriteClose(code, reason)
+	if err != nil {
+		return err
+	}
+	c.conn.SetReadDeadline(time.Now().Add(closeHandshakeTimeout))
+	for {
+		f, err := c.readFrame()
+		if err != nil {
+			return err
+		}
+		if f.opcode == opClose {
+			return nil
+		}
+	}
+}
+
+type frame struct {
+	fin     bool
+	opcode  opcode
+	payload []byte
+}
+
+func (c *Conn) readFrame() (*frame, error) {
+	var head [2]byte
+	_, err := io.ReadFull(c.reader, head[:])
+	if err != nil {
+		return nil, err
+	}
+	f := &frame{
+		fin:    head[0]&0x80 != 0,
+		opcode: opcode(head[0] & 0x0F),
+	}
+	if head[0]&0x70 != 0 {
+		return nil, fmt.Errorf("%w: reserved bits set", ErrProtocol)
+	}
+	switch f.opcode {
+	case opContinuation, opText, opBinary, opClose, opPing, opPong:
+	default:
+		return nil, fmt.Errorf("%w: unknown opcode %d", ErrProtocol, f.opcode)
+	}
+	if head[1]&0x80 == 0 {
+		return nil, fmt.Errorf("%w: client frames must be masked", ErrProtocol)
+	}
+
+	length := uint64(head[1] & 0x7F)
+	switch length {
+	case 126:
+		var ext [2]byte
+		_, err = io.ReadFull(c.reader, ext[:])
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		_, err = io.ReadFull(c.reader, ext[:])
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+	if err != nil {
+		return nil, err
+	}
+	if f.opcode >= opClose && (length > maxControlPayload || !f.fin) {
+		return nil, fmt.Errorf("%w: invalid control frame", ErrProtocol)
+	}
+	if length > uint64(c.readLimit) {
+		return nil, ErrMessageTooBig
+	}
+
+	var mask [4]byte
+	_, err = io.ReadFull(c.reader, mask[:])
+	if err != nil {
+		return nil, err
+	}
+	f.payload = make([]byte, length)
+	_, err = io.ReadFull(c.reader, f.payload)
+	if err != nil {
+		return nil, err
+	}
+	maskBytes(mask, f.payload)
+	return f, nil
+}
+
+func (c *Conn) writeFrame(fin bool, op opcode, payload []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if c.closeSent {
+		return ErrClosed
+	}
+	_, err := c.conn.Write(appendFrame(nil, fin, op, payload))
+	return err
+}
+
+// appendFrame encodes an unmasked frame, as sent by servers.
+func appendFrame(b []byte, fin bool, op opcode, payload []byte) []byte {
+	first := byte(op)
+	if fin {
+		first |= 0x80
+	}
+	b = append(b, first)
+	switch {
+	case len(payload) < 126:
+		b = append(b, byte(len(payload)))
+	case len(payload) <= 0xFFFF:
+		b = append(b, 126)
+		b = binary.BigEndian.AppendUint16(b, uint16(len(payload)))
+	default:
+		b = append(b, 127)
+		b = binary.BigEndian.AppendUint64(b, uint64(len(payload)))
+	}
+	return append(b, payload...)
+}
+
+func maskBytes(key [4]byte, b []byte) {
+	for i := range b {
+		b[i] ^= key[i%4]
+	}
+}
+
+func parseClosePayload(payload []byte) (*CloseError, error) {
+	if len(payload) == 0 {
+		return &CloseError{Code: CloseNoStatusReceived}, nil
+	}
+	if len(payload) == 1 {
+		return nil, fmt.Errorf("%w: truncated close payload", ErrProtocol)
+	}
+	code := int(binary.BigEndian.Uint16(payload))
+	if !validCloseCode(code) {
+		return nil, fmt.Errorf("%w: invalid close code %d", ErrProtocol, code)
+	}
+	reason := payload[2:]
+	if !utf8.Valid(reason) {
+		return nil, errInvalidUTF8
+	}
+	return &CloseError{Code: code, Reason: string(reason)}, nil
+}
+
+func validCloseCode(code int) bool {
+	switch {
+	case code >= 1000 && code <= 1003,
+		code >= 1007 && code <= 1011,
+		code >= 3000 && code <= 4999:
+		return true
+	}
+	return false
+}
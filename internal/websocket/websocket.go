@@ -0,0 +1,95 @@
+package websocket
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/UUest/httpfromtcp/internal/headers"
+	"github.com/UUest/httpfromtcp/internal/request"
+	"github.com/UUest/httpfromtcp/internal/response"
+)
+
+// acceptGUID is the fixed value from RFC 6455 section 1.3 that is appended to
+// Sec-WebSocket-Key before hashing.
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+const supportedVersion = "13"
+
+var ErrBadHandshake = errors.New("websocket: bad handshake")
+
+// Upgrade validates the opening handshake in req, writes the 101 Switching
+// Protocols response and takes over the connection. If the handshake is
+// invalid an error response is written and ErrBadHandshake is returned.
+// The Conn outlives the handler, so it can be passed to a goroutine; close it
+// with Close when done.
+func Upgrade(w *response.Writer, req *request.Request) (*Conn, error) {
+	if err := checkHandshake(req); err != nil {
+		writeHandshakeError(w, response.StatusCodeBadRequest, err)
+		return nil, err
+	}
+	if version, _ := req.Headers.Get("Sec-WebSocket-Version"); version != supportedVersion {
+		err := fmt.Errorf("%w: unsupported Sec-WebSocket-Version %q", ErrBadHandshake, version)
+		writeHandshakeError(w, response.StatusCodeUpgradeRequired, err)
+		return nil, err
+	}
+	key, _ := req.Headers.Get("Sec-WebSocket-Key")
+
+	err := w.WriteStatusLine(response.StatusCodeSwitchingProtocols)
+	if err != nil {
+		return nil, err
+	}
+	h := headers.NewHeaders()
+	h.Set("Upgrade", "websocket")
+	h.Set("Connection", "Upgrade")
+	h.Set("Sec-WebSocket-Accept", acceptKey(key))
+	err = w.WriteHeaders(h)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := w.Hijack()
+	if err != nil {
+		return nil, err
+	}
+	return newConn(conn), nil
+}
+
+func checkHandshake(req *request.Request) error {
+	if req.RequestLine.Method != "GET" {
+		return fmt.Errorf("%w: method must be GET", ErrBadHandshake)
+	}
+	if !req.Headers.HasToken("Upgrade", "websocket") {
+		return fmt.Errorf("%w: missing Upgrade: websocket", ErrBadHandshake)
+	}
+	if !req.Headers.HasToken("Connection", "upgrade") {
+		return fmt.Errorf("%w: missing Connection: Upgrade", ErrBadHandshake)
+	}
+	key, ok := req.Headers.Get("Sec-WebSocket-Key")
+	if !ok {
+		return fmt.Errorf("%w: missing Sec-WebSocket-Key", ErrBadHandshake)
+	}
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(key))
+	if err != nil || len(decoded) != 16 {
+		return fmt.Errorf("%w: invalid Sec-WebSocket-Key %q", ErrBadHandshake, key)
+	}
+	return nil
+}
+
+func writeHandshakeError(w *response.Writer, statusCode response.StatusCode, err error) {
+	w.WriteStatusLine(statusCode)
+	body := []byte(err.Error())
+	h := response.GetDefaultHeaders(len(body))
+	if statusCode == response.StatusCodeUpgradeRequired {
+		h.Set("Sec-WebSocket-Version", supportedVersion)
+	}
+	w.WriteHeaders(h)
+	w.WriteBody(body)
+}
+
+// acceptKey computes the Sec-WebSocket-Accept value for a client key.
+func acceptKey(key string) string {
+	sum := sha1.Sum([]byte(strings.TrimSpace(key) + acceptGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
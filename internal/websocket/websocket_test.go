@@ -0,0 +1,192 @@
+package websocket
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/UUest/httpfromtcp/internal/request"
+	"github.com/UUest/httpfromtcp/internal/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const handshake = "GET /chat HTTP/1.1\r\n" +
+	"Host: localhost:42069\r\n" +
+	"Upgrade: websocket\r\n" +
+	"Connection: keep-alive, Upgrade\r\n" +
+	"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n" +
+	"Sec-WebSocket-Version: 13\r\n" +
+	"\r\n"
+
+// clientFrame encodes a masked frame the way a browser would send it.
+func clientFrame(fin bool, op opcode, payload []byte) []byte {
+	first := byte(op)
+	if fin {
+		first |= 0x80
+	}
+	b := []byte{first}
+	switch {
+	case len(payload) < 126:
+		b = append(b, 0x80|byte(len(payload)))
+	default:
+		b = append(b, 0x80|126)
+		b = binary.BigEndian.AppendUint16(b, uint16(len(payload)))
+	}
+	key := [4]byte{0x37, 0xfa, 0x21, 0x3d}
+	b = append(b, key[:]...)
+	masked := append([]byte(nil), payload...)
+	maskBytes(key, masked)
+	return append(b, masked...)
+}
+
+// upgrade performs the handshake over an in-memory pipe and returns the
+// server side Conn plus a reader over what the server sends to the client.
+func upgrade(t *testing.T) (*Conn, net.Conn, *bufio.Reader) {
+	serverSide, clientSide := net.Pipe()
+	t.Cleanup(func() {
+		serverSide.Close()
+		clientSide.Close()
+	})
+	req, err := request.RequestFromReader(strings.NewReader(handshake))
+	require.NoError(t, err)
+
+	clientReader := bufio.NewReader(clientSide)
+	type result struct {
+		conn *Conn
+		err  error
+	}
+	done := make(chan result)
+	go func() {
+		c, err := Upgrade(response.NewWriter(serverSide), req)
+		done <- result{c, err}
+	}()
+
+	status, err := clientReader.ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "HTTP/1.1 101 Switching Protocols\r\n", status)
+	var sawAccept bool
+	for {
+		line, err := clientReader.ReadString('\n')
+		require.NoError(t, err)
+		if line == "\r\n" {
+			break
+		}
+		if strings.HasPrefix(line, "sec-websocket-accept: ") {
+			sawAccept = true
+			assert.Equal(t, "sec-websocket-accept: s3pPLMBiTxaQ9kYGzzhZRbK+xOo=\r\n", line)
+		}
+	}
+	assert.True(t, sawAccept)
+
+	r := <-done
+	require.NoError(t, r.err)
+	return r.conn, clientSide, clientReader
+}
+
+func TestAcceptKey(t *testing.T) {
+	// Test: Example from RFC 6455 section 1.3
+	assert.Equal(t, "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=", acceptKey("dGhlIHNhbXBsZSBub25jZQ=="))
+}
+
+func TestUpgradeRejectsBadHandshake(t *testing.T) {
+	// Test: Missing Sec-WebSocket-Key
+	data := strings.Replace(handshake, "Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n", "", 1)
+	req, err := request.RequestFromReader(strings.NewReader(data))
+	require.NoError(t, err)
+	var out strings.Builder
+	_, err = Upgrade(response.NewWriter(&out), req)
+	require.ErrorIs(t, err, ErrBadHandshake)
+	assert.True(t, strings.HasPrefix(out.String(), "HTTP/1.1 400 Bad Request\r\n"))
+
+	// Test: Unsupported version
+	data = strings.Replace(handshake, "Sec-WebSocket-Version: 13", "Sec-WebSocket-Version: 8", 1)
+	req, err = request.RequestFromReader(strings.NewReader(data))
+	require.NoError(t, err)
+	out.Reset()
+	_, err = Upgrade(response.NewWriter(&out), req)
+	require.ErrorIs(t, err, ErrBadHandshake)
+	assert.True(t, strings.HasPrefix(out.String(), "HTTP/1.1 426 Upgrade Required\r\n"))
+	assert.Contains(t, out.String(), "sec-websocket-version: 13\r\n")
+}
+
+func TestReadMessage(t *testing.T) {
+	conn, client, clientReader := upgrade(t)
+
+	// Test: Fragmented text message with an interleaved ping
+	go func() {
+		client.Write(clientFrame(false, opText, []byte("Hel")))
+		client.Write(clientFrame(true, opPing, []byte("hi")))
+		client.Write(clientFrame(true, opContinuation, []byte("lo")))
+	}()
+	pong := make([]byte, 4)
+	pongDone := make(chan struct{})
+	go func() {
+		io.ReadFull(clientReader, pong)
+		close(pongDone)
+	}()
+	mt, msg, err := conn.ReadMessage()
+	require.NoError(t, err)
+	assert.Equal(t, TextMessage, mt)
+	assert.Equal(t, "Hello", string(msg))
+	<-pongDone
+	assert.Equal(t, []byte{0x8A, 0x02, 'h', 'i'}, pong)
+
+	// Test: Close handshake initiated by the client
+	go client.Write(clientFrame(true, opClose, []byte{0x03, 0xE8}))
+	closeFrame := make([]byte, 4)
+	closeDone := make(chan struct{})
+	go func() {
+		io.ReadFull(clientReader, closeFrame)
+		close(closeDone)
+	}()
+	_, _, err = conn.ReadMessage()
+	var closeErr *CloseError
+	require.ErrorAs(t, err, &closeErr)
+	assert.Equal(t, CloseNormalClosure, closeErr.Code)
+	<-closeDone
+	assert.Equal(t, []byte{0x88, 0x02, 0x03, 0xE8}, closeFrame)
+}
+
+func TestReadLimit(t *testing.T) {
+	conn, client, clientReader := upgrade(t)
+	conn.SetReadLimit(4)
+
+	// Test: Message over the limit closes with 1009
+	go client.Write(clientFrame(true, opBinary, []byte("too long")))
+	closeFrame := make([]byte, 4)
+	closeDone := make(chan struct{})
+	go func() {
+		io.ReadFull(clientReader, closeFrame)
+		close(closeDone)
+	}()
+	_, _, err := conn.ReadMessage()
+	require.ErrorIs(t, err, ErrMessageTooBig)
+	<-closeDone
+	assert.Equal(t, []byte{0x88, 0x02, 0x03, 0xF1}, closeFrame)
+}
+
+func TestUnmaskedFrameRejected(t *testing.T) {
+	conn, client, clientReader := upgrade(t)
+
+	// Test: Unmasked client frame is a protocol error
+	go client.Write(appendFrame(nil, true, opText, []byte("hi")))
+	go clientReader.Read(make([]byte, 4))
+	_, _, err := conn.ReadMessage()
+	require.ErrorIs(t, err, ErrProtocol)
+}
+
+func TestWriteMessageFragments(t *testing.T) {
+	conn, _, clientReader := upgrade(t)
+	conn.SetWriteFragmentSize(3)
+
+	// Test: Payload split into text + continuation frames
+	go conn.WriteMessage(TextMessage, []byte("hello"))
+	got := make([]byte, 9)
+	_, err := io.ReadFull(clientReader, got)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0x01, 0x03, 'h', 'e', 'l', 0x80, 0x02, 'l', 'o'}, got)
+}